@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	AlertmanagerAlertsPath = "/api/v2/alerts"
+	AlertmanagerTimeout    = 10 * time.Second
+
+	ValidatorMissingBlocksAlert = "ValidatorMissingBlocks"
+	ValidatorJailedAlert        = "ValidatorJailed"
+	ValidatorTombstonedAlert    = "ValidatorTombstoned"
+)
+
+// AlertmanagerReporter pushes an alert for each validator missing blocks, jailed
+// or tombstoned, based on the latest chains state. Alerts are pushed on every report
+// and every RefreshInterval, each time with endsAt moved ResolveTimeout forward,
+// and are ended once the validator recovers, or expire if the checker is down.
+// Jailed and tombstoned validators are only alerted about for JailedAlertsPeriod,
+// so validators jailed long ago don't fire forever.
+type AlertmanagerReporter struct {
+	AlertmanagerConfig AlertmanagerConfig
+	Chains             []*Chain
+	Logger             zerolog.Logger
+
+	Client *http.Client
+
+	// Alerts pushed last time for each chain, by their labels, to keep their start time
+	// and to end the ones which are not firing anymore.
+	mutex  sync.Mutex
+	alerts map[string]map[string]AlertmanagerAlert
+	// When validators were reported as jailed or tombstoned, keyed by chain and address.
+	// Not persisted, so only used if the jail time cannot be calculated from the state.
+	jailedAt map[string]time.Time
+}
+
+type AlertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+func NewAlertmanagerReporter(
+	alertmanagerConfig AlertmanagerConfig,
+	chains []*Chain,
+	logger *zerolog.Logger,
+) *AlertmanagerReporter {
+	return &AlertmanagerReporter{
+		AlertmanagerConfig: alertmanagerConfig,
+		Chains:             chains,
+		Logger:             logger.With().Str("component", "alertmanager_reporter").Logger(),
+		alerts:             make(map[string]map[string]AlertmanagerAlert),
+		jailedAt:           make(map[string]time.Time),
+	}
+}
+
+func GetAlertmanagerJailedKey(chain, address string) string {
+	return chain + "/" + address
+}
+
+// IsRecentlyJailed checks whether the validator was jailed less than JailedAlertsPeriod ago.
+// For validators jailed for downtime, it's calculated from the jail end time, otherwise
+// it's known only if the jail was reported since the start.
+func (r *AlertmanagerReporter) IsRecentlyJailed(chain string, validator ValidatorState, params Params) bool {
+	period := time.Duration(r.AlertmanagerConfig.JailedAlertsPeriod) * time.Second
+	if period <= 0 {
+		return true
+	}
+
+	jailedAt, ok := r.jailedAt[GetAlertmanagerJailedKey(chain, validator.Address)]
+	if !ok && !validator.JailedForDoubleSign() && !validator.JailedUntil.IsZero() {
+		jailedAt, ok = validator.JailedUntil.Add(-params.DowntimeJailDuration), true
+	}
+
+	return ok && time.Since(jailedAt) < period
+}
+
+func GetAlertmanagerAlertKey(alert AlertmanagerAlert) string {
+	labels, _ := json.Marshal(alert.Labels)
+	return string(labels)
+}
+
+// GetChainAlerts returns the alerts firing for the chain validators, without
+// the start and end time set. Should be called with the mutex held.
+func (r *AlertmanagerReporter) GetChainAlerts(chain *Chain) []AlertmanagerAlert {
+	status := chain.GetStatus()
+	groups := chain.GetMissedBlocksGroups()
+	alerts := []AlertmanagerAlert{}
+
+	for _, validator := range status.State {
+		groupIndex, err := groups.GetGroupIndex(validator.MissedBlocks)
+		if err != nil {
+			r.Logger.Warn().
+				Err(err).
+				Str("address", validator.Address).
+				Msg("Could not get validator missed blocks group")
+			continue
+		}
+
+		var (
+			alertName   string
+			direction   Direction
+			description string
+			eta         string
+		)
+
+		if (validator.Tombstoned || validator.Jailed) &&
+			!r.IsRecentlyJailed(chain.Config.GetName(), validator, status.Params) {
+			continue
+		}
+
+		switch {
+		case validator.Tombstoned:
+			alertName, direction, description = ValidatorTombstonedAlert, TOMBSTONED, TombstonedDesc
+		case validator.Jailed:
+			alertName, direction = ValidatorJailedAlert, JAILED
+			description = validator.GetJailedDesc(status.Params.DowntimeJailDuration)
+		case groupIndex > 0:
+			alertName, direction, description = ValidatorMissingBlocksAlert, INCREASING, groups[groupIndex].DescStart
+
+			if status.Params.AvgBlockTimeKnown() {
+				entry := ReportEntry{MissingBlocks: validator.MissedBlocks}
+				eta = entry.GetTimeToJailDesc(&status.Params)
+			}
+		default:
+			continue
+		}
+
+		labels := map[string]string{
+			"alertname": alertName,
+			"chain":     chain.Config.GetName(),
+			"validator": validator.Address,
+			"moniker":   validator.Moniker,
+			"group":     strconv.Itoa(groupIndex),
+			"direction": direction.String(),
+		}
+		for name, value := range r.AlertmanagerConfig.Labels {
+			labels[name] = value
+		}
+
+		validatorURL := chain.Config.ChainInfoConfig.GetValidatorURL(validator.Address)
+		annotations := map[string]string{
+			"description": fmt.Sprintf("%s %s", validator.Moniker, description),
+			"explorer":    validatorURL,
+		}
+		if eta != "" {
+			annotations["eta"] = eta
+		}
+
+		alerts = append(alerts, AlertmanagerAlert{
+			Labels:       labels,
+			Annotations:  annotations,
+			GeneratorURL: validatorURL,
+		})
+	}
+
+	return alerts
+}
+
+// PushChainAlerts pushes the firing alerts with endsAt moved forward, and the ones
+// firing last time but not anymore with endsAt set to now, so they are resolved.
+func (r *AlertmanagerReporter) PushChainAlerts(chain *Chain) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	endsAt := now.Add(time.Duration(r.AlertmanagerConfig.ResolveTimeout) * time.Second)
+	chainName := chain.Config.GetName()
+
+	previousAlerts := r.alerts[chainName]
+	firingAlerts := make(map[string]AlertmanagerAlert)
+	alerts := []AlertmanagerAlert{}
+
+	for _, alert := range r.GetChainAlerts(chain) {
+		key := GetAlertmanagerAlertKey(alert)
+
+		alert.StartsAt = now
+		if previousAlert, ok := previousAlerts[key]; ok {
+			alert.StartsAt = previousAlert.StartsAt
+		}
+		alert.EndsAt = endsAt
+
+		firingAlerts[key] = alert
+		alerts = append(alerts, alert)
+	}
+
+	for key, alert := range previousAlerts {
+		if _, ok := firingAlerts[key]; !ok {
+			alert.EndsAt = now
+			alerts = append(alerts, alert)
+		}
+	}
+
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	if err := r.send(alerts); err != nil {
+		return err
+	}
+
+	r.alerts[chainName] = firingAlerts
+	return nil
+}
+
+// Refresh pushes the alerts of all the chains every RefreshInterval. Never returns.
+func (r *AlertmanagerReporter) Refresh() {
+	ticker := time.NewTicker(time.Duration(r.AlertmanagerConfig.RefreshInterval) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, chain := range r.Chains {
+			if err := r.PushChainAlerts(chain); err != nil {
+				r.Logger.Error().
+					Err(err).
+					Str("chain", chain.Config.GetName()).
+					Msg("Could not refresh alerts")
+			}
+		}
+	}
+}
+
+func (r *AlertmanagerReporter) Serialize(report Report) string {
+	for _, chain := range r.Chains {
+		if chain.Config.GetName() != report.GetChainName() {
+			continue
+		}
+
+		r.mutex.Lock()
+		alerts, err := json.Marshal(r.GetChainAlerts(chain))
+		r.mutex.Unlock()
+
+		if err != nil {
+			r.Logger.Error().Err(err).Msg("Could not serialize report")
+			return ""
+		}
+
+		return string(alerts)
+	}
+
+	return ""
+}
+
+func (r *AlertmanagerReporter) Init() {
+	if r.AlertmanagerConfig.URL == "" {
+		r.Logger.Debug().Msg("Alertmanager URL not set, not creating Alertmanager reporter.")
+		return
+	}
+
+	r.Client = &http.Client{Timeout: AlertmanagerTimeout}
+	go r.Refresh()
+}
+
+func (r *AlertmanagerReporter) Enabled() bool {
+	return r.AlertmanagerConfig.URL != ""
+}
+
+// SendReport remembers the jails reported and pushes the alerts of the report chain,
+// as its state has just been updated.
+func (r *AlertmanagerReporter) SendReport(report Report) error {
+	r.mutex.Lock()
+	for _, entry := range report.Entries {
+		key := GetAlertmanagerJailedKey(report.GetChainName(), entry.ValidatorAddress)
+
+		switch entry.Direction {
+		case JAILED, TOMBSTONED:
+			if _, ok := r.jailedAt[key]; !ok || entry.Direction == TOMBSTONED {
+				r.jailedAt[key] = time.Now()
+			}
+		case UNJAILED:
+			delete(r.jailedAt, key)
+		}
+	}
+	r.mutex.Unlock()
+
+	for _, chain := range r.Chains {
+		if chain.Config.GetName() == report.GetChainName() {
+			return r.PushChainAlerts(chain)
+		}
+	}
+
+	return fmt.Errorf("chain %s not found", report.GetChainName())
+}
+
+func (r *AlertmanagerReporter) send(alerts []AlertmanagerAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(r.AlertmanagerConfig.URL, "/") + AlertmanagerAlertsPath
+	response, err := r.Client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("got status %d: %s", response.StatusCode, responseBody)
+	}
+
+	return nil
+}
+
+func (r *AlertmanagerReporter) Name() string {
+	return "AlertmanagerReporter"
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// API serves what the checker knows about the chains as JSON, so other services
+// can use it. All the endpoints are read-only:
+//   - /healthz - whether the last poll of each chain succeeded, 503 if not
+//   - /chains - the list of chains
+//   - /chains/<chain>/validators - the latest validators state
+//   - /chains/<chain>/validators/<address>/history?from=<RFC3339>&to=<RFC3339> - the stored
+//     validator history, if the database is enabled
+//   - /chains/<chain>/params - the current chain params
+//   - /chains/<chain>/missed-blocks-groups - the missed blocks groups used
+//   - /chains/<chain>/reports - the last reports generated
+type API struct {
+	Config   APIConfig
+	Chains   []*Chain
+	Database *Database
+	Logger   zerolog.Logger
+}
+
+type HealthResponse struct {
+	Healthy bool                   `json:"healthy"`
+	Chains  map[string]ChainHealth `json:"chains"`
+}
+
+type ChainHealth struct {
+	Healthy      bool      `json:"healthy"`
+	LastPollTime time.Time `json:"last_poll_time"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type APIValidator struct {
+	Address          string     `json:"address"`
+	Moniker          string     `json:"moniker"`
+	ConsensusAddress string     `json:"consensus_address"`
+	MissedBlocks     int64      `json:"missed_blocks"`
+	Jailed           bool       `json:"jailed"`
+	Active           bool       `json:"active"`
+	Tombstoned       bool       `json:"tombstoned"`
+	JailedUntil      *time.Time `json:"jailed_until,omitempty"`
+	StartHeight      int64      `json:"start_height"`
+	IndexOffset      int64      `json:"index_offset"`
+}
+
+type APIValidatorSnapshot struct {
+	Height    int64        `json:"height"`
+	Time      time.Time    `json:"time"`
+	Validator APIValidator `json:"validator"`
+}
+
+type APIParams struct {
+	// Not set if the block time is not known yet.
+	AvgBlockTimeSeconds         *float64 `json:"avg_block_time_seconds,omitempty"`
+	AvgBlockTimeSamples         int64    `json:"avg_block_time_samples"`
+	SignedBlocksWindow          int64    `json:"signed_blocks_window"`
+	MissedBlocksToJail          int64    `json:"missed_blocks_to_jail"`
+	DowntimeJailDurationSeconds float64  `json:"downtime_jail_duration_seconds"`
+}
+
+type APIMissedBlocksGroup struct {
+	Start      int64  `json:"start"`
+	End        int64  `json:"end"`
+	EmojiStart string `json:"emoji_start"`
+	EmojiEnd   string `json:"emoji_end"`
+	DescStart  string `json:"desc_start"`
+	DescEnd    string `json:"desc_end"`
+}
+
+func NewAPIValidator(state ValidatorState) APIValidator {
+	validator := APIValidator{
+		Address:          state.Address,
+		Moniker:          state.Moniker,
+		ConsensusAddress: state.ConsensusAddress,
+		MissedBlocks:     state.MissedBlocks,
+		Jailed:           state.Jailed,
+		Active:           state.Active,
+		Tombstoned:       state.Tombstoned,
+		StartHeight:      state.StartHeight,
+		IndexOffset:      state.IndexOffset,
+	}
+
+	if !state.JailedUntil.IsZero() {
+		jailedUntil := state.JailedUntil
+		validator.JailedUntil = &jailedUntil
+	}
+
+	return validator
+}
+
+// NewAPIValidators returns the validators sorted by operator address.
+func NewAPIValidators(state ValidatorsState) []APIValidator {
+	validators := make([]APIValidator, 0, len(state))
+	for _, validatorState := range state {
+		validators = append(validators, NewAPIValidator(validatorState))
+	}
+
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Address < validators[j].Address
+	})
+
+	return validators
+}
+
+func NewAPIValidatorHistory(snapshots []ValidatorSnapshot) []APIValidatorSnapshot {
+	history := make([]APIValidatorSnapshot, len(snapshots))
+	for index, snapshot := range snapshots {
+		history[index] = APIValidatorSnapshot{
+			Height:    snapshot.Height,
+			Time:      snapshot.Time,
+			Validator: NewAPIValidator(snapshot.State),
+		}
+	}
+
+	return history
+}
+
+func NewAPIParams(params Params) APIParams {
+	apiParams := APIParams{
+		AvgBlockTimeSamples:         params.AvgBlockTimeSamples,
+		SignedBlocksWindow:          params.SignedBlocksWindow,
+		MissedBlocksToJail:          params.MissedBlocksToJail,
+		DowntimeJailDurationSeconds: params.DowntimeJailDuration.Seconds(),
+	}
+
+	if params.AvgBlockTimeKnown() {
+		avgBlockTime := params.AvgBlockTime
+		apiParams.AvgBlockTimeSeconds = &avgBlockTime
+	}
+
+	return apiParams
+}
+
+func NewAPIMissedBlocksGroups(groups MissedBlocksGroups) []APIMissedBlocksGroup {
+	apiGroups := make([]APIMissedBlocksGroup, len(groups))
+	for index, group := range groups {
+		apiGroups[index] = APIMissedBlocksGroup{
+			Start:      group.Start,
+			End:        group.End,
+			EmojiStart: group.EmojiStart,
+			EmojiEnd:   group.EmojiEnd,
+			DescStart:  group.DescStart,
+			DescEnd:    group.DescEnd,
+		}
+	}
+
+	return apiGroups
+}
+
+// NewAPIReports returns the reports in the same format they are sent to webhooks.
+func NewAPIReports(reports []Report) []WebhookPayload {
+	payloads := make([]WebhookPayload, len(reports))
+	for index, report := range reports {
+		payloads[index] = NewWebhookPayload(report)
+	}
+
+	return payloads
+}
+
+func NewAPI(config APIConfig, chains []*Chain, database *Database, logger *zerolog.Logger) *API {
+	return &API{
+		Config:   config,
+		Chains:   chains,
+		Database: database,
+		Logger:   logger.With().Str("component", "api").Logger(),
+	}
+}
+
+func (a *API) Enabled() bool {
+	return a.Config.ListenAddress != ""
+}
+
+// Start serves the API on the configured address. Never returns if enabled.
+func (a *API) Start() {
+	if !a.Enabled() {
+		a.Logger.Debug().Msg("API listen address not set, not starting API server.")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.handleHealth)
+	mux.HandleFunc("/chains", a.handleChains)
+	mux.HandleFunc("/chains/", a.handleChain)
+
+	a.Logger.Info().Str("address", a.Config.ListenAddress).Msg("Serving API")
+	if err := http.ListenAndServe(a.Config.ListenAddress, mux); err != nil {
+		a.Logger.Fatal().Err(err).Msg("Could not start API server")
+	}
+}
+
+func (a *API) handleHealth(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{
+		Healthy: true,
+		Chains:  make(map[string]ChainHealth, len(a.Chains)),
+	}
+
+	for _, chain := range a.Chains {
+		status := chain.GetStatus()
+		response.Chains[chain.Config.GetName()] = ChainHealth{
+			Healthy:      status.LastPollSucceeded,
+			LastPollTime: status.LastPollTime,
+		}
+
+		if !status.LastPollSucceeded {
+			response.Healthy = false
+		}
+	}
+
+	if !response.Healthy {
+		a.writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, response)
+}
+
+func (a *API) handleChains(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, len(a.Chains))
+	for index, chain := range a.Chains {
+		names[index] = chain.Config.GetName()
+	}
+
+	a.writeJSON(w, http.StatusOK, names)
+}
+
+func (a *API) handleChain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		a.writeError(w, http.StatusMethodNotAllowed, "only GET requests are supported")
+		return
+	}
+
+	// /chains/<chain>/<resource>[/<address>/history]
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/chains/"), "/"), "/")
+	if len(parts) < 2 {
+		a.writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	chain, err := FindChainByName(a.Chains, parts[0])
+	if err != nil {
+		a.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "validators":
+		a.writeJSON(w, http.StatusOK, NewAPIValidators(chain.GetStatus().State))
+	case len(parts) == 4 && parts[1] == "validators" && parts[3] == "history":
+		a.handleValidatorHistory(w, r, chain, parts[2])
+	case len(parts) == 2 && parts[1] == "params":
+		a.writeJSON(w, http.StatusOK, NewAPIParams(chain.GetParams()))
+	case len(parts) == 2 && parts[1] == "missed-blocks-groups":
+		a.writeJSON(w, http.StatusOK, NewAPIMissedBlocksGroups(chain.GetMissedBlocksGroups()))
+	case len(parts) == 2 && parts[1] == "reports":
+		a.writeJSON(w, http.StatusOK, NewAPIReports(chain.GetStatus().Reports))
+	default:
+		a.writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleValidatorHistory returns the validator history for the last day,
+// unless from and/or to are specified.
+func (a *API) handleValidatorHistory(w http.ResponseWriter, r *http.Request, chain *Chain, address string) {
+	if !a.Database.Enabled() {
+		a.writeError(w, http.StatusNotFound, "database is not enabled")
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+
+	for param, value := range map[string]*time.Time{"from": &from, "to": &to} {
+		if raw := r.URL.Query().Get(param); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				a.writeError(w, http.StatusBadRequest, "invalid "+param+": "+err.Error())
+				return
+			}
+
+			*value = parsed
+		}
+	}
+
+	history, err := a.Database.GetValidatorHistory(chain.Config.Name, address, from, to)
+	if err != nil {
+		a.Logger.Error().Err(err).Str("address", address).Msg("Could not get validator history")
+		a.writeError(w, http.StatusInternalServerError, "could not get validator history")
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, NewAPIValidatorHistory(history))
+}
+
+func (a *API) writeError(w http.ResponseWriter, status int, message string) {
+	a.writeJSON(w, status, ErrorResponse{Error: message})
+}
+
+func (a *API) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		a.Logger.Error().Err(err).Msg("Could not write API response")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Weight of a single block in the average block time, so roughly the last
+// 1 / BlockTimeWeight blocks affect the estimate.
+const BlockTimeWeight = 0.01
+
+// BlockTimeEstimator keeps an exponentially weighted average of the block time over
+// the blocks observed. Instead of relying on a single block far in the past, which
+// may be pruned, it's updated every poll, so it works on pruned and freshly state-synced
+// nodes too, just with fewer samples at first.
+type BlockTimeEstimator struct {
+	RPC    *TendermintRPC
+	Logger zerolog.Logger
+
+	mutex      sync.Mutex
+	lastHeight int64
+	lastTime   time.Time
+	estimate   float64
+	samples    int64
+}
+
+func NewBlockTimeEstimator(rpc *TendermintRPC, logger *zerolog.Logger) *BlockTimeEstimator {
+	return &BlockTimeEstimator{
+		RPC:    rpc,
+		Logger: logger.With().Str("component", "block_time_estimator").Logger(),
+	}
+}
+
+// Seed makes the initial estimate from the block BlocksDiffInThePast heights back,
+// or the earliest block the node has, if it's more recent.
+func (e *BlockTimeEstimator) Seed() error {
+	status, err := e.RPC.GetStatus()
+	if err != nil {
+		return err
+	}
+
+	latestHeight := status.SyncInfo.LatestBlockHeight
+	pastHeight := latestHeight - e.RPC.BlocksDiffInThePast
+	if pastHeight < status.SyncInfo.EarliestBlockHeight {
+		pastHeight = status.SyncInfo.EarliestBlockHeight
+	}
+
+	if pastHeight > 0 && pastHeight < latestHeight {
+		if block, err := e.RPC.GetBlock(&pastHeight); err != nil {
+			e.Logger.Warn().
+				Err(err).
+				Int64("height", pastHeight).
+				Msg("Could not get past block, estimating block time from new blocks only")
+		} else {
+			e.Observe(block.Height, block.Time)
+		}
+	}
+
+	e.Observe(latestHeight, status.SyncInfo.LatestBlockTime)
+	return nil
+}
+
+// Update observes the latest block.
+func (e *BlockTimeEstimator) Update() error {
+	status, err := e.RPC.GetStatus()
+	if err != nil {
+		return err
+	}
+
+	e.Observe(status.SyncInfo.LatestBlockHeight, status.SyncInfo.LatestBlockTime)
+	return nil
+}
+
+// Observe adds the time passed since the previously observed block to the estimate,
+// weighted by the amount of blocks produced in between.
+func (e *BlockTimeEstimator) Observe(height int64, blockTime time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.lastHeight == 0 || height <= e.lastHeight {
+		if height > e.lastHeight {
+			e.lastHeight = height
+			e.lastTime = blockTime
+		}
+
+		return
+	}
+
+	blocks := height - e.lastHeight
+	interval := blockTime.Sub(e.lastTime).Seconds() / float64(blocks)
+
+	if e.samples == 0 {
+		e.estimate = interval
+	} else {
+		weight := 1 - math.Pow(1-BlockTimeWeight, float64(blocks))
+		e.estimate = e.estimate*(1-weight) + interval*weight
+	}
+
+	e.samples += blocks
+	e.lastHeight = height
+	e.lastTime = blockTime
+
+	e.Logger.Trace().
+		Float64("avgBlockTime", e.estimate).
+		Int64("samples", e.samples).
+		Msg("Block time estimate updated")
+}
+
+// Estimate returns the average block time in seconds and the amount of blocks
+// it's based on, 0 meaning there is no estimate yet.
+func (e *BlockTimeEstimator) Estimate() (float64, int64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.estimate, e.samples
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBlockTimeEstimator(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type block struct {
+		height int64
+		time   time.Time
+	}
+
+	testCases := []struct {
+		name             string
+		blocks           []block
+		expectedEstimate float64
+		expectedSamples  int64
+	}{
+		{
+			name:             "no estimate without blocks",
+			expectedEstimate: 0,
+			expectedSamples:  0,
+		},
+		{
+			name:             "no estimate from a single block",
+			blocks:           []block{{100, start}},
+			expectedEstimate: 0,
+			expectedSamples:  0,
+		},
+		{
+			name: "estimates from the first two blocks",
+			blocks: []block{
+				{100, start},
+				{110, start.Add(60 * time.Second)},
+			},
+			expectedEstimate: 6,
+			expectedSamples:  10,
+		},
+		{
+			name: "weights new blocks",
+			blocks: []block{
+				{100, start},
+				{110, start.Add(60 * time.Second)},
+				{111, start.Add(76 * time.Second)},
+			},
+			expectedEstimate: 6*(1-BlockTimeWeight) + 16*BlockTimeWeight,
+			expectedSamples:  11,
+		},
+		{
+			name: "weights blocks by the amount produced in between",
+			blocks: []block{
+				{100, start},
+				{110, start.Add(60 * time.Second)},
+				{112, start.Add(92 * time.Second)},
+			},
+			expectedEstimate: 6*math.Pow(1-BlockTimeWeight, 2) + 16*(1-math.Pow(1-BlockTimeWeight, 2)),
+			expectedSamples:  12,
+		},
+		{
+			name: "ignores blocks already observed",
+			blocks: []block{
+				{100, start},
+				{110, start.Add(60 * time.Second)},
+				{110, start.Add(120 * time.Second)},
+				{105, start.Add(30 * time.Second)},
+			},
+			expectedEstimate: 6,
+			expectedSamples:  10,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			estimator := NewBlockTimeEstimator(nil, &logger)
+
+			for _, block := range testCase.blocks {
+				estimator.Observe(block.height, block.time)
+			}
+
+			estimate, samples := estimator.Estimate()
+			if math.Abs(estimate-testCase.expectedEstimate) > 1e-9 || samples != testCase.expectedSamples {
+				t.Errorf(
+					"expected %f from %d samples, got %f from %d samples",
+					testCase.expectedEstimate,
+					testCase.expectedSamples,
+					estimate,
+					samples,
+				)
+			}
+		})
+	}
+}
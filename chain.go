@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/rs/zerolog"
+)
+
+// Chain holds everything needed to monitor a single chain, each chain
+// is monitored in its own loop, sharing reporters with the other ones.
+type Chain struct {
+	Config             *ChainConfig
+	Params             *Params
+	RPC                *TendermintRPC
+	GRPC               *TendermintGRPC
+	ReportGenerator    *ReportGenerator
+	Listener           *NewBlocksListener
+	ParamsRefresher    *ParamsRefresher
+	BlockTimeEstimator *BlockTimeEstimator
+	Metrics            *Metrics
+	Logger             zerolog.Logger
+
+	// Params as of the last refresh, to compare the next refreshed ones with,
+	// as the average block time in Params is updated every poll.
+	RefreshedParams Params
+
+	// Set if missed blocks groups are not configured, so they should be
+	// recalculated when the signed blocks window changes.
+	DefaultMissedBlocksGroups bool
+	// Guards Params, MissedBlocksGroups and the last polls info, which are updated
+	// in the chain loop while being read from other goroutines, like Telegram commands.
+	mutex sync.RWMutex
+	// Last poll info, exposed via the API.
+	lastPollTime      time.Time
+	lastPollSucceeded bool
+	lastState         ValidatorsState
+	lastReports       []Report
+}
+
+// ChainStatus is the latest info about the chain the checker has.
+type ChainStatus struct {
+	LastPollTime      time.Time
+	LastPollSucceeded bool
+	State             ValidatorsState
+	Params            Params
+	// Last non-empty reports generated, the oldest first.
+	Reports []Report
+}
+
+func NewChain(
+	config *ChainConfig,
+	database *Database,
+	metrics *Metrics,
+	registry codectypes.InterfaceRegistry,
+	logger *zerolog.Logger,
+) *Chain {
+	chainLogger := logger.With().Str("chain", config.Name).Logger()
+
+	if len(config.IncludeValidators) == 0 && len(config.ExcludeValidators) == 0 {
+		chainLogger.Info().Msg("Monitoring all validators")
+	} else if len(config.IncludeValidators) != 0 {
+		chainLogger.Info().
+			Strs("validators", config.IncludeValidators).
+			Msg("Monitoring specific validators")
+	} else {
+		chainLogger.Info().
+			Strs("validators", config.ExcludeValidators).
+			Msg("Monitoring all validators except specific")
+	}
+
+	rpc := NewTendermintRPC(config.NodeConfig, config.ConsensusNodePrefix, &chainLogger)
+	grpc := NewTendermintGRPC(config, metrics, registry, &chainLogger)
+
+	blockTimeEstimator := NewBlockTimeEstimator(rpc, &chainLogger)
+	paramsRefresher := NewParamsRefresher(
+		grpc,
+		blockTimeEstimator,
+		time.Duration(config.ParamsRefreshInterval)*time.Second,
+		&chainLogger,
+	)
+
+	params := &Params{}
+	defaultMissedBlocksGroups := config.MissedBlocksGroups == nil
+
+	stateStore := NewStateStore(config.StatePath, &chainLogger)
+	reportGenerator := NewReportGenerator(
+		params,
+		grpc,
+		rpc,
+		stateStore,
+		database,
+		metrics,
+		config,
+		&chainLogger,
+		registry,
+	)
+	reportGenerator.LoadState()
+
+	listener := NewNewBlocksListener(
+		rpc.Nodes,
+		config.BlocksInterval,
+		time.Duration(config.Interval)*time.Second,
+		&chainLogger,
+	)
+
+	return &Chain{
+		Config:             config,
+		Params:             params,
+		RPC:                rpc,
+		GRPC:               grpc,
+		ReportGenerator:    reportGenerator,
+		Listener:           listener,
+		ParamsRefresher:    paramsRefresher,
+		BlockTimeEstimator: blockTimeEstimator,
+		Metrics:            metrics,
+		Logger:             chainLogger,
+
+		DefaultMissedBlocksGroups: defaultMissedBlocksGroups,
+	}
+}
+
+func (c *Chain) GetParams() Params {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return *c.Params
+}
+
+func (c *Chain) GetMissedBlocksGroups() MissedBlocksGroups {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.Config.MissedBlocksGroups
+}
+
+func (c *Chain) GetStatus() ChainStatus {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return ChainStatus{
+		LastPollTime:      c.lastPollTime,
+		LastPollSucceeded: c.lastPollSucceeded,
+		State:             c.lastState,
+		Params:            *c.Params,
+		Reports:           append([]Report{}, c.lastReports...),
+	}
+}
+
+// SetPollResult stores the poll result, keeping no more than ReportsHistory last reports.
+func (c *Chain) SetPollResult(report *Report, succeeded bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lastPollTime = time.Now()
+	c.lastPollSucceeded = succeeded
+	// The state is replaced and not modified after each poll, so it's safe to share.
+	c.lastState = c.ReportGenerator.State
+
+	if report.Empty() || c.Config.ReportsHistory <= 0 {
+		return
+	}
+
+	c.lastReports = append(c.lastReports, *report)
+	if len(c.lastReports) > c.Config.ReportsHistory {
+		c.lastReports = c.lastReports[len(c.lastReports)-c.Config.ReportsHistory:]
+	}
+}
+
+// UpdateBlockTime observes the latest block and updates the average block time in Params.
+func (c *Chain) UpdateBlockTime() {
+	if err := c.BlockTimeEstimator.Update(); err != nil {
+		c.Logger.Warn().Err(err).Msg("Could not update block time estimate")
+		return
+	}
+
+	avgBlockTime, samples := c.BlockTimeEstimator.Estimate()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Params.AvgBlockTime = avgBlockTime
+	c.Params.AvgBlockTimeSamples = samples
+}
+
+// ApplyParams updates the params with the refreshed ones, recalculating default
+// missed blocks groups or checking the configured ones still cover the signed blocks window.
+// Returns the changes worth announcing and the alerts about the groups not being valid anymore.
+func (c *Chain) ApplyParams(params Params) ([]ParamChange, []MonitoringAlert) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	changes := GetParamsChanges(c.RefreshedParams, params)
+	windowChanged := c.Params.SignedBlocksWindow != params.SignedBlocksWindow
+	c.RefreshedParams = params
+	*c.Params = params
+
+	if len(changes) > 0 {
+		c.Logger.Info().
+			Int64("signedBlocksWindow", params.SignedBlocksWindow).
+			Int64("missedBlocksToJail", params.MissedBlocksToJail).
+			Float64("avgBlockTime", params.AvgBlockTime).
+			Msg("Chain params changed")
+	}
+
+	if !windowChanged {
+		return changes, nil
+	}
+
+	if c.DefaultMissedBlocksGroups {
+		c.Config.MissedBlocksGroups = nil
+		c.Config.SetDefaultMissedBlocksGroups(params)
+		return changes, nil
+	}
+
+	if err := c.Config.MissedBlocksGroups.Validate(params.SignedBlocksWindow); err != nil {
+		c.Logger.Error().Err(err).Msg("MissedBlockGroups config is invalid for the new params")
+		return changes, []MonitoringAlert{{
+			Emoji:       MonitoringDegradedEmoji,
+			Description: fmt.Sprintf("Missed blocks groups config is invalid for the new params: %s", err),
+		}}
+	}
+
+	return changes, nil
+}
+
+// Init estimates the block time and fetches the chain params.
+func (c *Chain) Init() error {
+	if err := c.BlockTimeEstimator.Seed(); err != nil {
+		return err
+	}
+
+	params, err := c.ParamsRefresher.GetParams()
+	if err != nil {
+		return err
+	}
+
+	c.Logger.Info().
+		Int64("missedBlocksToJail", params.MissedBlocksToJail).
+		Float64("avgBlockTime", params.AvgBlockTime).
+		Int64("avgBlockTimeSamples", params.AvgBlockTimeSamples).
+		Msg("Chain params calculated")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	*c.Params = params
+	c.RefreshedParams = params
+
+	c.Config.SetDefaultMissedBlocksGroups(params)
+
+	return nil
+}
+
+// WaitForInit calls Init every poll until it succeeds, counting each failure as a failed poll,
+// so a chain which nodes are down since the start gets the monitoring degraded alert too,
+// without blocking the other chains.
+func (c *Chain) WaitForInit(reporters []Reporter) {
+	for {
+		err := c.Init()
+		if err == nil {
+			return
+		}
+
+		c.Logger.Error().Err(err).Msg("Could not calculate chain params")
+		c.Metrics.IncPollErrors(c.Config.GetName())
+
+		report := c.ReportGenerator.NewReport(c.ReportGenerator.GetFailedPollAlerts(err), nil, nil, nil)
+		c.SetPollResult(report, false)
+		if !report.Empty() {
+			c.SendReport(reporters, report)
+		}
+
+		c.Listener.Wait()
+	}
+}
+
+// SendReport sends the report to all the enabled reporters.
+func (c *Chain) SendReport(reporters []Reporter, report *Report) {
+	for _, reporter := range reporters {
+		if !reporter.Enabled() {
+			c.Logger.Debug().Str("name", reporter.Name()).Msg("Reporter is disabled.")
+			continue
+		}
+
+		c.Logger.Info().Str("name", reporter.Name()).Msg("Sending a report to reporter...")
+		if err := reporter.SendReport(*report); err != nil {
+			c.Logger.Error().Err(err).Str("name", reporter.Name()).Msg("Could not send message")
+			c.Metrics.IncReporterFailures(c.Config.GetName(), reporter.Name())
+		}
+	}
+}
+
+// Start generates reports and sends them to reporters. Only returns if the configured
+// missed blocks groups don't cover the signed blocks window, which is only known once
+// the params are fetched, alerting about it and leaving the other chains monitored.
+func (c *Chain) Start(reporters []Reporter) {
+	c.WaitForInit(reporters)
+
+	if err := c.GetMissedBlocksGroups().Validate(c.GetParams().SignedBlocksWindow); err != nil {
+		c.Logger.Error().Err(err).Msg("MissedBlockGroups config is invalid, not monitoring the chain")
+		c.SendReport(reporters, c.ReportGenerator.NewReport([]MonitoringAlert{{
+			Emoji:       MonitoringDegradedEmoji,
+			Description: fmt.Sprintf("Missed blocks groups config is invalid, not monitoring the chain: %s", err),
+		}}, nil, nil, nil))
+		return
+	}
+
+	// With a single node there is nothing to fail over to.
+	healthCheckInterval := time.Duration(c.Config.NodeConfig.HealthCheckInterval) * time.Second
+	if len(c.GRPC.Nodes.Addresses) > 1 {
+		go c.GRPC.Nodes.StartHealthChecks(healthCheckInterval)
+	}
+	if len(c.RPC.Nodes.Addresses) > 1 {
+		go c.RPC.Nodes.StartHealthChecks(healthCheckInterval)
+	}
+
+	if c.Config.ListenNewBlocks {
+		go c.Listener.Listen()
+	}
+
+	if c.ParamsRefresher.Interval > 0 {
+		go c.ParamsRefresher.Start()
+	}
+
+	for {
+		// Applying the refreshed params here, so they don't change while generating a report.
+		var (
+			paramsChanges []ParamChange
+			paramsAlerts  []MonitoringAlert
+		)
+
+		select {
+		case params := <-c.ParamsRefresher.Updates:
+			paramsChanges, paramsAlerts = c.ApplyParams(params)
+		default:
+		}
+
+		c.UpdateBlockTime()
+		c.Metrics.SetParams(c.Config.GetName(), c.GetParams())
+
+		pollStart := time.Now()
+		report := c.ReportGenerator.GenerateReport()
+		c.Metrics.ObservePoll(c.Config.GetName(), time.Since(pollStart))
+		report.ParamsChanges = paramsChanges
+		report.MonitoringAlerts = append(report.MonitoringAlerts, paramsAlerts...)
+		c.SetPollResult(report, c.ReportGenerator.FailedPolls == 0)
+
+		if report.Empty() {
+			c.Logger.Info().Msg("Report is empty, not sending.")
+			c.Listener.Wait()
+			continue
+		}
+
+		c.SendReport(reporters, report)
+
+		c.Listener.Wait()
+	}
+}
+
+// Stop waits for the poll in progress to finish and saves the chain state,
+// not polling the chain anymore.
+func (c *Chain) Stop() {
+	c.Logger.Info().Msg("Stopping, saving state")
+	c.ReportGenerator.Stop()
+}
+
+func FindChainByName(chains []*Chain, name string) (*Chain, error) {
+	for _, chain := range chains {
+		if chain.Config.GetName() == name {
+			return chain, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not find chain %s", name)
+}
+
+// FindChainByValidatorAddress finds the chain by the validator address bech prefix.
+func FindChainByValidatorAddress(chains []*Chain, address string) (*Chain, error) {
+	for _, chain := range chains {
+		if chain.Config.IsValidatorAddress(address) {
+			return chain, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not find chain for validator %s", address)
+}
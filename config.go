@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"html"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/mcuadros/go-defaults"
@@ -20,6 +24,62 @@ type SlackConfig struct {
 	Chat  string `toml:"chat"`
 }
 
+type DiscordConfig struct {
+	WebhookURL string `toml:"webhook-url"`
+	Token      string `toml:"token"`
+	Channel    string `toml:"channel"`
+}
+
+type WebhookConfig struct {
+	URLs     []string          `toml:"urls"`
+	Headers  map[string]string `toml:"headers"`
+	Secret   string            `toml:"secret"`
+	Timeout  int               `toml:"timeout" default:"10"`
+	Attempts int               `toml:"attempts" default:"3"`
+}
+
+type PagerDutyConfig struct {
+	RoutingKey string `toml:"routing-key"`
+	URL        string `toml:"url" default:"https://events.pagerduty.com/v2/enqueue"`
+	// Severities of incidents triggered when a validator enters a missed blocks group,
+	// by the group index, and on other events, by the direction name.
+	GroupSeverities     map[string]string `toml:"group-severities"`
+	DirectionSeverities map[string]string `toml:"direction-severities"`
+
+	// Parsed GroupSeverities and DirectionSeverities.
+	groupSeverities     map[int]string
+	directionSeverities map[Direction]string
+}
+
+type AlertmanagerConfig struct {
+	URL             string            `toml:"url"`
+	Labels          map[string]string `toml:"labels"`
+	RefreshInterval int               `toml:"refresh-interval" default:"60"`
+	ResolveTimeout  int               `toml:"resolve-timeout" default:"300"`
+	// For how long after being jailed or tombstoned validators are alerted about, 0 meaning forever.
+	JailedAlertsPeriod int `toml:"jailed-alerts-period" default:"86400"`
+}
+
+type EmailConfig struct {
+	Host       string   `toml:"host"`
+	Port       int      `toml:"port" default:"587"`
+	Encryption string   `toml:"encryption" default:"starttls"`
+	Username   string   `toml:"username"`
+	Password   string   `toml:"password"`
+	From       string   `toml:"from"`
+	To         []string `toml:"to"`
+	// Recipients getting only the entries about the validators, by the validator address.
+	ValidatorsRecipients map[string][]string `toml:"validators-recipients"`
+}
+
+const (
+	EmailEncryptionSTARTTLS = "starttls"
+	EmailEncryptionTLS      = "tls"
+	EmailEncryptionNone     = "none"
+)
+
+var PagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
 type LogConfig struct {
 	LogLevel   string `toml:"level" default:"info"`
 	JSONOutput bool   `toml:"json" default:"false"`
@@ -30,33 +90,112 @@ type ChainInfoConfig struct {
 	ValidatorPagePattern string `toml:"validator-page-pattern"`
 }
 
-func (c *ChainInfoConfig) GetValidatorPage(address string, text string) string {
+func (c *ChainInfoConfig) GetValidatorURL(address string) string {
 	// non-mintscan links
 	if c.ValidatorPagePattern != "" {
-		href := fmt.Sprintf(c.ValidatorPagePattern, address)
-		return fmt.Sprintf("<a href=\"%s\">%s</a>", href, html.EscapeString(text))
+		return fmt.Sprintf(c.ValidatorPagePattern, address)
 	}
 
-	return fmt.Sprintf(
-		"<a href=\"https://www.mintscan.io/%s/validators/%s\">%s</a>",
-		c.MintscanPrefix,
-		address,
-		html.EscapeString(text),
-	)
+	return fmt.Sprintf("https://www.mintscan.io/%s/validators/%s", c.MintscanPrefix, address)
+}
+
+func (c *ChainInfoConfig) GetValidatorPage(address string, text string) string {
+	return fmt.Sprintf("<a href=\"%s\">%s</a>", c.GetValidatorURL(address), html.EscapeString(text))
+}
+
+type APIConfig struct {
+	ListenAddress string `toml:"listen-address"`
+}
+
+type MetricsConfig struct {
+	ListenAddress string `toml:"listen-address"`
+}
+
+type DatabaseConfig struct {
+	Path          string `toml:"path"`
+	RetentionDays int    `toml:"retention-days" default:"7"`
+}
+
+type MissedBlocksStreakConfig struct {
+	Threshold  int64            `toml:"threshold"`
+	Validators map[string]int64 `toml:"validators"`
+}
+
+// GetThreshold returns the amount of consecutive missed blocks to alert on for a validator,
+// 0 means streak alerts are disabled for it.
+func (c *MissedBlocksStreakConfig) GetThreshold(address string) int64 {
+	if threshold, ok := c.Validators[address]; ok {
+		return threshold
+	}
+
+	return c.Threshold
+}
+
+func (c *MissedBlocksStreakConfig) Enabled() bool {
+	if c.Threshold > 0 {
+		return true
+	}
+
+	for _, threshold := range c.Validators {
+		if threshold > 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 type NodeConfig struct {
-	GrpcAddress   string `toml:"grpc-address" default:"localhost:9090"`
-	TendermintRPC string `toml:"rpc-address" default:"http://localhost:26657"`
+	GrpcAddress         string   `toml:"grpc-address" default:"localhost:9090"`
+	TendermintRPC       string   `toml:"rpc-address" default:"http://localhost:26657"`
+	GrpcAddresses       []string `toml:"grpc-addresses"`
+	TendermintRPCs      []string `toml:"rpc-addresses"`
+	FailoverPolicy      string   `toml:"failover-policy" default:"priority"`
+	HealthCheckInterval int      `toml:"health-check-interval" default:"30"`
+	PageSize            uint64   `toml:"page-size" default:"1000"`
+	Timeout             int      `toml:"timeout" default:"30"`
 }
 
-type AppConfig struct {
-	LogConfig       LogConfig       `toml:"log"`
+// GetGrpcAddresses returns the gRPC nodes to use, falling back to grpc-address
+// if grpc-addresses is not set.
+func (c *NodeConfig) GetGrpcAddresses() []string {
+	if len(c.GrpcAddresses) == 0 {
+		return []string{c.GrpcAddress}
+	}
+
+	return c.GrpcAddresses
+}
+
+// GetTendermintRPCs returns the Tendermint RPC nodes to use, falling back to rpc-address
+// if rpc-addresses is not set.
+func (c *NodeConfig) GetTendermintRPCs() []string {
+	if len(c.TendermintRPCs) == 0 {
+		return []string{c.TendermintRPC}
+	}
+
+	return c.TendermintRPCs
+}
+
+// ChainConfig is the config of a single chain to monitor.
+type ChainConfig struct {
+	Name            string          `toml:"name"`
 	ChainInfoConfig ChainInfoConfig `toml:"chain-info"`
 	NodeConfig      NodeConfig      `toml:"node"`
 
-	QueryEachSigningInfo bool `toml:"query-each-signing-info"`
-	Interval             int  `toml:"interval" default:"120"`
+	QueryEachSigningInfo   bool     `toml:"query-each-signing-info"`
+	SigningInfoWorkers     int      `toml:"signing-info-workers" default:"10"`
+	SigningInfoTimeout     int      `toml:"signing-info-timeout" default:"10"`
+	Interval               int      `toml:"interval" default:"120"`
+	StatePath              string   `toml:"state-path"`
+	TrackBlockSignatures   bool     `toml:"track-block-signatures"`
+	MaxBlocksPerPoll       int64    `toml:"max-blocks-per-poll" default:"1000"`
+	ListenNewBlocks        bool     `toml:"listen-new-blocks"`
+	BlocksInterval         int64    `toml:"blocks-interval" default:"20"`
+	FailedPollsThreshold   int64    `toml:"failed-polls-threshold" default:"5"`
+	ParamsRefreshInterval  int      `toml:"params-refresh-interval" default:"3600"`
+	JailWarningThresholds  []string `toml:"jail-warning-thresholds"`
+	UnjailReminderInterval int      `toml:"unjail-reminder-interval"`
+	ReportsHistory         int      `toml:"reports-history" default:"20"`
 
 	Prefix                    string `toml:"bech-prefix"`
 	ValidatorPrefix           string `toml:"bech-validator-prefix"`
@@ -67,10 +206,31 @@ type AppConfig struct {
 	IncludeValidators []string `toml:"include-validators"`
 	ExcludeValidators []string `toml:"exclude-validators"`
 
-	MissedBlocksGroups MissedBlocksGroups `toml:"missed-blocks-groups"`
+	MissedBlocksGroups       MissedBlocksGroups       `toml:"missed-blocks-groups"`
+	MissedBlocksStreakConfig MissedBlocksStreakConfig `toml:"missed-blocks-streak"`
 
-	TelegramConfig TelegramAppConfig `toml:"telegram"`
-	SlackConfig    SlackConfig       `toml:"slack"`
+	// Parsed JailWarningThresholds, sorted from the largest to the smallest one.
+	jailWarningThresholds []time.Duration
+}
+
+type AppConfig struct {
+	LogConfig      LogConfig      `toml:"log"`
+	DatabaseConfig DatabaseConfig `toml:"database"`
+	MetricsConfig  MetricsConfig  `toml:"metrics"`
+	APIConfig      APIConfig      `toml:"api"`
+
+	// A single chain config at the top level, kept for configs written
+	// before monitoring multiple chains was supported. Ignored if Chains is set.
+	ChainConfig
+	Chains []ChainConfig `toml:"chains"`
+
+	TelegramConfig     TelegramAppConfig  `toml:"telegram"`
+	SlackConfig        SlackConfig        `toml:"slack"`
+	WebhookConfig      WebhookConfig      `toml:"webhook"`
+	DiscordConfig      DiscordConfig      `toml:"discord"`
+	PagerDutyConfig    PagerDutyConfig    `toml:"pagerduty"`
+	AlertmanagerConfig AlertmanagerConfig `toml:"alertmanager"`
+	EmailConfig        EmailConfig        `toml:"email"`
 }
 
 type MissedBlocksGroup struct {
@@ -90,6 +250,20 @@ type MissedBlocksGroups []MissedBlocksGroup
 // 0 - 99, 100 - 199, 200 - 300 - valid
 // 0 - 50 - not valid.
 func (g MissedBlocksGroups) Validate(window int64) error {
+	if err := g.ValidateRanges(); err != nil {
+		return err
+	}
+
+	if g[len(g)-1].End < window {
+		return fmt.Errorf("last MissedBlocksGroup's end should be >= %d, got %d", window, g[len(g)-1].End)
+	}
+
+	return nil
+}
+
+// ValidateRanges checks the groups without knowing the signed blocks window,
+// so it can be done before fetching the chain params.
+func (g MissedBlocksGroups) ValidateRanges() error {
 	if len(g) == 0 {
 		return fmt.Errorf("MissedBlocksGroups is empty")
 	}
@@ -98,10 +272,6 @@ func (g MissedBlocksGroups) Validate(window int64) error {
 		return fmt.Errorf("first MissedBlocksGroup's start should be 0, got %d", g[0].Start)
 	}
 
-	if g[len(g)-1].End < window {
-		return fmt.Errorf("last MissedBlocksGroup's end should be >= %d, got %d", window, g[len(g)-1].End)
-	}
-
 	for i := 0; i < len(g)-1; i++ {
 		if g[i+1].Start-g[i].End != 1 {
 			return fmt.Errorf(
@@ -117,13 +287,22 @@ func (g MissedBlocksGroups) Validate(window int64) error {
 }
 
 func (g MissedBlocksGroups) GetGroup(missed int64) (*MissedBlocksGroup, error) {
-	for _, group := range g {
+	index, err := g.GetGroupIndex(missed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &g[index], nil
+}
+
+func (g MissedBlocksGroups) GetGroupIndex(missed int64) (int, error) {
+	for index, group := range g {
 		if missed >= group.Start && missed <= group.End {
-			return &group, nil
+			return index, nil
 		}
 	}
 
-	return nil, fmt.Errorf("could not find a group for missed blocks counter = %d", missed)
+	return 0, fmt.Errorf("could not find a group for missed blocks counter = %d", missed)
 }
 
 func LoadConfig(path string) (*AppConfig, error) {
@@ -135,21 +314,265 @@ func LoadConfig(path string) (*AppConfig, error) {
 	configString := string(configBytes)
 
 	configStruct := AppConfig{}
-	if _, err = toml.Decode(configString, &configStruct); err != nil {
+	metadata, err := toml.Decode(configString, &configStruct)
+	if err != nil {
 		return nil, err
 	}
 
+	// Defaults replace zero values, while setting the retention to 0 explicitly disables pruning.
+	retentionDays := configStruct.DatabaseConfig.RetentionDays
 	defaults.SetDefaults(&configStruct)
+	if metadata.IsDefined("database", "retention-days") {
+		configStruct.DatabaseConfig.RetentionDays = retentionDays
+	}
+
 	return &configStruct, nil
 }
 
+// GetChains returns the list of chains to monitor, which is either the chains list,
+// or the top-level chain config, if the list is not set.
+func (config *AppConfig) GetChains() []*ChainConfig {
+	if len(config.Chains) == 0 {
+		return []*ChainConfig{&config.ChainConfig}
+	}
+
+	chains := make([]*ChainConfig, len(config.Chains))
+	for index := range config.Chains {
+		chains[index] = &config.Chains[index]
+	}
+
+	return chains
+}
+
+// Redacted returns a copy of the config with the secrets, like tokens and passwords,
+// replaced, so it can be logged.
+func (config AppConfig) Redacted() AppConfig {
+	config.TelegramConfig.Token = redactString(config.TelegramConfig.Token)
+	config.SlackConfig.Token = redactString(config.SlackConfig.Token)
+	config.DiscordConfig.Token = redactString(config.DiscordConfig.Token)
+	config.DiscordConfig.WebhookURL = redactString(config.DiscordConfig.WebhookURL)
+	config.WebhookConfig.Secret = redactString(config.WebhookConfig.Secret)
+	config.PagerDutyConfig.RoutingKey = redactString(config.PagerDutyConfig.RoutingKey)
+	config.EmailConfig.Password = redactString(config.EmailConfig.Password)
+
+	// Headers usually carry credentials, like Authorization.
+	headers := make(map[string]string, len(config.WebhookConfig.Headers))
+	for name, value := range config.WebhookConfig.Headers {
+		headers[name] = redactString(value)
+	}
+	config.WebhookConfig.Headers = headers
+
+	return config
+}
+
+func redactString(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return "<redacted>"
+}
+
 func (config *AppConfig) Validate() {
+	chains := config.GetChains()
+	names := make(map[string]bool, len(chains))
+
+	for _, chain := range chains {
+		if len(chains) > 1 && chain.Name == "" {
+			GetDefaultLogger().Fatal().Msg("Each chain should have a name when monitoring multiple chains!")
+		}
+
+		if chain.Name == DefaultChainBucket {
+			GetDefaultLogger().Fatal().
+				Str("name", chain.Name).
+				Msg("Chain name is reserved for a chain without a name!")
+		}
+
+		if names[chain.Name] {
+			GetDefaultLogger().Fatal().Str("name", chain.Name).Msg("Chain names should be unique!")
+		}
+
+		names[chain.Name] = true
+		chain.Validate()
+	}
+
+	config.DatabaseConfig.Validate()
+	config.WebhookConfig.Validate()
+	config.PagerDutyConfig.Validate()
+	config.AlertmanagerConfig.Validate()
+	config.EmailConfig.Validate()
+}
+
+func (config *EmailConfig) Validate() {
+	if config.Encryption != EmailEncryptionSTARTTLS &&
+		config.Encryption != EmailEncryptionTLS &&
+		config.Encryption != EmailEncryptionNone {
+		GetDefaultLogger().Fatal().
+			Str("encryption", config.Encryption).
+			Msg("Unsupported email encryption, expected starttls, tls or none!")
+	}
+}
+
+func (config *AlertmanagerConfig) Validate() {
+	if config.ResolveTimeout <= config.RefreshInterval {
+		GetDefaultLogger().Fatal().
+			Int("refresh-interval", config.RefreshInterval).
+			Int("resolve-timeout", config.ResolveTimeout).
+			Msg("Alertmanager resolve timeout should be larger than the refresh interval!")
+	}
+}
+
+func (config *DatabaseConfig) Validate() {
+	if config.RetentionDays < 0 {
+		GetDefaultLogger().Fatal().
+			Int("retention-days", config.RetentionDays).
+			Msg("Database retention should not be negative!")
+	}
+}
+
+func (config *WebhookConfig) Validate() {
+	if config.Attempts < 1 {
+		GetDefaultLogger().Fatal().
+			Int("attempts", config.Attempts).
+			Msg("Webhook attempts should be at least 1!")
+	}
+
+	if config.Timeout <= 0 {
+		GetDefaultLogger().Fatal().
+			Int("timeout", config.Timeout).
+			Msg("Webhook timeout should be positive!")
+	}
+}
+
+// Validate parses the severities, using the default ones if not set:
+// error for any missed blocks group except the first one, and critical
+// for jailed and tombstoned validators.
+func (config *PagerDutyConfig) Validate() {
+	if config.GroupSeverities != nil {
+		config.groupSeverities = make(map[int]string, len(config.GroupSeverities))
+		for group, severity := range config.GroupSeverities {
+			index, err := strconv.Atoi(group)
+			if err != nil || index < 1 {
+				GetDefaultLogger().Fatal().
+					Str("group", group).
+					Msg("PagerDuty group severities keys should be missed blocks group indexes starting from 1!")
+			}
+
+			config.groupSeverities[index] = config.validateSeverity(severity)
+		}
+	}
+
+	if config.DirectionSeverities == nil {
+		config.DirectionSeverities = map[string]string{
+			"jailed":     "critical",
+			"tombstoned": "critical",
+		}
+	}
+
+	config.directionSeverities = make(map[Direction]string, len(config.DirectionSeverities))
+	for name, severity := range config.DirectionSeverities {
+		direction, ok := ParseDirection(name)
+		if !ok || !IsPagerDutyTriggerDirection(direction) {
+			GetDefaultLogger().Fatal().
+				Str("direction", name).
+				Msg("Unsupported PagerDuty direction severity, expected jailed, tombstoned, offline or jail_warning, " +
+					"use group-severities for missed blocks!")
+		}
+
+		config.directionSeverities[direction] = config.validateSeverity(severity)
+	}
+}
+
+func (config *PagerDutyConfig) validateSeverity(severity string) string {
+	if !stringInSlice(severity, PagerDutySeverities) {
+		GetDefaultLogger().Fatal().
+			Str("severity", severity).
+			Msg("PagerDuty severity should be one of critical, error, warning or info!")
+	}
+
+	return severity
+}
+
+// GetGroupSeverity returns the severity for a validator entering the missed blocks group,
+// and false if it should not trigger an incident.
+func (config *PagerDutyConfig) GetGroupSeverity(group int) (string, bool) {
+	if config.groupSeverities == nil {
+		return "error", group > 0
+	}
+
+	severity, ok := config.groupSeverities[group]
+	return severity, ok
+}
+
+func (config *PagerDutyConfig) GetDirectionSeverity(direction Direction) (string, bool) {
+	severity, ok := config.directionSeverities[direction]
+	return severity, ok
+}
+
+func (config *ChainConfig) Validate() {
 	if len(config.IncludeValidators) != 0 && len(config.ExcludeValidators) != 0 {
 		GetDefaultLogger().Fatal().Msg("Cannot use --include and --exclude at the same time!")
 	}
+
+	if config.MissedBlocksStreakConfig.Enabled() && !config.TrackBlockSignatures {
+		GetDefaultLogger().Fatal().Msg("Missed blocks streak alerts require track-block-signatures to be enabled!")
+	}
+
+	if config.MaxBlocksPerPoll < 1 {
+		GetDefaultLogger().Fatal().
+			Int64("blocks", config.MaxBlocksPerPoll).
+			Msg("max-blocks-per-poll should be at least 1!")
+	}
+
+	if config.SigningInfoWorkers < 1 {
+		GetDefaultLogger().Fatal().
+			Int("workers", config.SigningInfoWorkers).
+			Msg("signing-info-workers should be at least 1!")
+	}
+
+	if config.MissedBlocksGroups != nil {
+		if err := config.MissedBlocksGroups.ValidateRanges(); err != nil {
+			GetDefaultLogger().Fatal().Err(err).Msg("MissedBlockGroups config is invalid")
+		}
+	}
+
+	config.jailWarningThresholds = make([]time.Duration, len(config.JailWarningThresholds))
+	for index, threshold := range config.JailWarningThresholds {
+		duration, err := time.ParseDuration(threshold)
+		if err != nil || duration <= 0 {
+			GetDefaultLogger().Fatal().
+				Str("threshold", threshold).
+				Msg("Jail warning thresholds should be positive durations, like 30m or 2h!")
+		}
+
+		config.jailWarningThresholds[index] = duration
+	}
+
+	sort.Slice(config.jailWarningThresholds, func(i, j int) bool {
+		return config.jailWarningThresholds[i] > config.jailWarningThresholds[j]
+	})
+
+	if config.NodeConfig.Timeout <= 0 {
+		GetDefaultLogger().Fatal().
+			Int("timeout", config.NodeConfig.Timeout).
+			Msg("Node timeout should be positive!")
+	}
+
+	if config.NodeConfig.FailoverPolicy != FailoverPolicyPriority &&
+		config.NodeConfig.FailoverPolicy != FailoverPolicyRoundRobin {
+		GetDefaultLogger().Fatal().
+			Str("policy", config.NodeConfig.FailoverPolicy).
+			Msg("Unsupported failover policy, expected priority or round-robin!")
+	}
 }
 
 func (config *AppConfig) SetBechPrefixes() {
+	for _, chain := range config.GetChains() {
+		chain.SetBechPrefixes()
+	}
+}
+
+func (config *ChainConfig) SetBechPrefixes() {
 	if config.Prefix == "" && config.ValidatorPrefix == "" {
 		GetDefaultLogger().Fatal().Msg("Both bech-validator-prefix and bech-prefix are not set!")
 	} else if config.ValidatorPrefix == "" {
@@ -175,7 +598,7 @@ func (config *AppConfig) SetBechPrefixes() {
 	}
 }
 
-func (config *AppConfig) SetDefaultMissedBlocksGroups(params Params) {
+func (config *ChainConfig) SetDefaultMissedBlocksGroups(params Params) {
 	if config.MissedBlocksGroups != nil {
 		GetDefaultLogger().Debug().Msg("MissedBlockGroups is set, not setting the default ones.")
 		return
@@ -208,7 +631,7 @@ func (config *AppConfig) SetDefaultMissedBlocksGroups(params Params) {
 	config.MissedBlocksGroups = groups
 }
 
-func (config *AppConfig) IsValidatorMonitored(address string) bool {
+func (config *ChainConfig) IsValidatorMonitored(address string) bool {
 	// If no args passed, we want to be notified about all validators.
 	if len(config.IncludeValidators) == 0 && len(config.ExcludeValidators) == 0 {
 		return true
@@ -234,3 +657,22 @@ func (config *AppConfig) IsValidatorMonitored(address string) bool {
 
 	return true
 }
+
+func (config *ChainConfig) GetJailWarningThresholds() []time.Duration {
+	return config.jailWarningThresholds
+}
+
+// GetName returns the chain name, or, if it's not set, which is possible when
+// monitoring a single chain, the Mintscan prefix.
+func (config *ChainConfig) GetName() string {
+	if config.Name != "" {
+		return config.Name
+	}
+
+	return config.ChainInfoConfig.MintscanPrefix
+}
+
+// IsValidatorAddress checks whether the address is a validator address of this chain.
+func (config *ChainConfig) IsValidatorAddress(address string) bool {
+	return strings.HasPrefix(address, config.ValidatorPrefix+"1")
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ValidatorsBucket = []byte("validators")
+
+// The bucket name used for a chain without a name, so a chain cannot be named so.
+const DefaultChainBucket = "default"
+
+const DatabasePruneInterval = time.Hour
+
+type ValidatorSnapshot struct {
+	Height int64
+	Time   time.Time
+	State  ValidatorState
+}
+
+// Database stores every polled validators state, so it's possible to find out
+// afterwards when a validator started missing blocks and how bad it was.
+// Data is stored in a separate bucket for each chain and validator operator address,
+// with snapshots keyed by the time they were taken.
+type Database struct {
+	DatabaseConfig DatabaseConfig
+	Logger         zerolog.Logger
+
+	DB *bolt.DB
+}
+
+func NewDatabase(databaseConfig DatabaseConfig, logger *zerolog.Logger) *Database {
+	return &Database{
+		DatabaseConfig: databaseConfig,
+		Logger:         logger.With().Str("component", "database").Logger(),
+	}
+}
+
+func (d *Database) Init() error {
+	if d.DatabaseConfig.Path == "" {
+		d.Logger.Debug().Msg("Database path not set, not storing validators history.")
+		return nil
+	}
+
+	db, err := bolt.Open(d.DatabaseConfig.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(ValidatorsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return err
+	}
+
+	d.DB = db
+	return nil
+}
+
+func (d *Database) Enabled() bool {
+	return d.DB != nil
+}
+
+func (d *Database) Close() {
+	if !d.Enabled() {
+		return
+	}
+
+	if err := d.DB.Close(); err != nil {
+		d.Logger.Error().Err(err).Msg("Could not close database")
+	}
+}
+
+func (d *Database) SaveSnapshot(chain string, snapshot StateSnapshot) error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	key := timeToKey(snapshot.Time)
+
+	return d.DB.Update(func(tx *bolt.Tx) error {
+		chainBucket, err := tx.Bucket(ValidatorsBucket).CreateBucketIfNotExists(chainToKey(chain))
+		if err != nil {
+			return err
+		}
+
+		for _, state := range snapshot.State {
+			bucket, err := chainBucket.CreateBucketIfNotExists([]byte(state.Address))
+			if err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(ValidatorSnapshot{
+				Height: snapshot.Height,
+				Time:   snapshot.Time,
+				State:  state,
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// StartPruning prunes the snapshots of all the chains every DatabasePruneInterval,
+// unless the retention is set to 0. Never returns if enabled.
+func (d *Database) StartPruning() {
+	if !d.Enabled() || d.DatabaseConfig.RetentionDays <= 0 {
+		d.Logger.Debug().Msg("Database or retention not set, not pruning old snapshots.")
+		return
+	}
+
+	ticker := time.NewTicker(DatabasePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Prune(); err != nil {
+			d.Logger.Error().Err(err).Msg("Could not prune old database entries")
+		}
+
+		<-ticker.C
+	}
+}
+
+// Prune removes all the snapshots older than the configured retention.
+func (d *Database) Prune() error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	retention := time.Duration(d.DatabaseConfig.RetentionDays) * 24 * time.Hour
+	cutoff := timeToKey(time.Now().Add(-retention))
+	pruned := 0
+
+	err := d.DB.Update(func(tx *bolt.Tx) error {
+		validatorsBucket := tx.Bucket(ValidatorsBucket)
+
+		return validatorsBucket.ForEach(func(chainName, _ []byte) error {
+			chainBucket := validatorsBucket.Bucket(chainName)
+			if chainBucket == nil {
+				return nil
+			}
+
+			return chainBucket.ForEach(func(name, _ []byte) error {
+				bucket := chainBucket.Bucket(name)
+				if bucket == nil {
+					return nil
+				}
+
+				cursor := bucket.Cursor()
+				for key, _ := cursor.First(); key != nil && bytes.Compare(key, cutoff) < 0; key, _ = cursor.First() {
+					if err := cursor.Delete(); err != nil {
+						return err
+					}
+
+					pruned++
+				}
+
+				return nil
+			})
+		})
+	})
+
+	if pruned > 0 {
+		d.Logger.Debug().Int("count", pruned).Msg("Pruned old validators snapshots")
+	}
+
+	return err
+}
+
+// GetValidatorHistory returns all the snapshots stored for a validator between the given times,
+// sorted by time ascending.
+func (d *Database) GetValidatorHistory(
+	chain string,
+	address string,
+	from, to time.Time,
+) ([]ValidatorSnapshot, error) {
+	snapshots := []ValidatorSnapshot{}
+
+	if !d.Enabled() {
+		return snapshots, nil
+	}
+
+	fromKey := timeToKey(from)
+	toKey := timeToKey(to)
+
+	err := d.DB.View(func(tx *bolt.Tx) error {
+		chainBucket := tx.Bucket(ValidatorsBucket).Bucket(chainToKey(chain))
+		if chainBucket == nil {
+			return nil
+		}
+
+		bucket := chainBucket.Bucket([]byte(address))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(fromKey); key != nil && bytes.Compare(key, toKey) <= 0; key, value = cursor.Next() {
+			var snapshot ValidatorSnapshot
+			if err := json.Unmarshal(value, &snapshot); err != nil {
+				return err
+			}
+
+			snapshots = append(snapshots, snapshot)
+		}
+
+		return nil
+	})
+
+	return snapshots, err
+}
+
+func timeToKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// chainToKey returns the chain bucket name, as bucket names cannot be empty
+// and the chain name can be omitted when monitoring a single chain.
+func chainToKey(chain string) []byte {
+	if chain == "" {
+		return []byte(DefaultChainBucket)
+	}
+
+	return []byte(chain)
+}
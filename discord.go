@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// Discord API limits, see https://discord.com/developers/docs/resources/channel#embed-object-embed-limits.
+const (
+	DiscordMaxContentLength          = 2000
+	DiscordMaxEmbedsPerMessage       = 10
+	DiscordMaxEmbedsLength           = 6000
+	DiscordMaxEmbedDescriptionLength = 4096
+	DiscordMaxAttempts               = 3
+	DiscordRequestTimeout            = 10 * time.Second
+)
+
+const DiscordAPIURL = "https://discord.com/api/v10"
+
+const (
+	DiscordColorGreen  = 0x2ecc71
+	DiscordColorYellow = 0xf1c40f
+	DiscordColorOrange = 0xe67e22
+	DiscordColorRed    = 0xe74c3c
+	DiscordColorBlue   = 0x3498db
+	DiscordColorGrey   = 0x95a5a6
+)
+
+var htmlTagsReplacer = strings.NewReplacer(
+	"<strong>", "**", "</strong>", "**",
+	"<i>", "*", "</i>", "*",
+	"<code>", "`", "</code>", "`",
+)
+
+var discordMarkdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"~", `\~`,
+	"|", `\|`,
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// Links or any other tags in the HTML used in reports.
+var htmlTokenRegexp = regexp.MustCompile(`<a href="([^"]*)">([^<]*)</a>|<[^>]+>`)
+
+// DiscordReporter sends reports via a Discord webhook or, if it's not set,
+// via a bot to a channel. Each entry is rendered as an embed coloured by its severity.
+type DiscordReporter struct {
+	DiscordConfig DiscordConfig
+	Logger        zerolog.Logger
+
+	Client *http.Client
+}
+
+type DiscordEmbed struct {
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+}
+
+type DiscordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []DiscordEmbed `json:"embeds,omitempty"`
+}
+
+type DiscordRateLimitResponse struct {
+	RetryAfter float64 `json:"retry_after"`
+}
+
+func NewDiscordReporter(
+	discordConfig DiscordConfig,
+	logger *zerolog.Logger,
+) *DiscordReporter {
+	return &DiscordReporter{
+		DiscordConfig: discordConfig,
+		Logger:        logger.With().Str("component", "discord_reporter").Logger(),
+	}
+}
+
+// EscapeDiscordMarkdown escapes the characters Discord would treat as formatting,
+// so monikers and descriptions are displayed as is.
+func EscapeDiscordMarkdown(text string) string {
+	return discordMarkdownEscaper.Replace(text)
+}
+
+// HTMLToDiscordMarkdown converts the HTML used in reports, like links produced
+// by ChainInfoConfig.GetValidatorPage, to Discord markdown, escaping the text
+// between the tags and the links texts.
+func HTMLToDiscordMarkdown(text string) string {
+	var sb strings.Builder
+
+	last := 0
+	for _, match := range htmlTokenRegexp.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(EscapeDiscordMarkdown(html.UnescapeString(text[last:match[0]])))
+
+		if match[2] >= 0 {
+			url := strings.ReplaceAll(html.UnescapeString(text[match[2]:match[3]]), ")", "%29")
+			linkText := EscapeDiscordMarkdown(html.UnescapeString(text[match[4]:match[5]]))
+			sb.WriteString(fmt.Sprintf("[%s](%s)", linkText, url))
+		} else {
+			sb.WriteString(htmlTagsReplacer.Replace(text[match[0]:match[1]]))
+		}
+
+		last = match[1]
+	}
+
+	sb.WriteString(EscapeDiscordMarkdown(html.UnescapeString(text[last:])))
+
+	return sb.String()
+}
+
+// GetDiscordColor returns the colour of the entry, from its missed blocks group relative
+// to the number of groups if the validator's missed blocks changed, or from its direction otherwise.
+func GetDiscordColor(entry ReportEntry, groupsCount int) int {
+	switch entry.Direction {
+	case INCREASING, DECREASING:
+		return GetDiscordGroupColor(entry.Group, groupsCount)
+	case JAILED, TOMBSTONED, OFFLINE:
+		return DiscordColorRed
+	case JAIL_WARNING, DEACTIVATED:
+		return DiscordColorOrange
+	case UNJAILED, ACTIVATED, ONLINE:
+		return DiscordColorGreen
+	case UNJAIL_READY:
+		return DiscordColorBlue
+	default:
+		return DiscordColorGrey
+	}
+}
+
+// GetDiscordGroupColor returns green for the first group, and yellow to red
+// for the other ones, spreading them evenly.
+func GetDiscordGroupColor(group, groupsCount int) int {
+	if group <= 0 || groupsCount <= 1 {
+		return DiscordColorGreen
+	}
+
+	colors := []int{DiscordColorYellow, DiscordColorOrange, DiscordColorRed}
+	index := (group - 1) * len(colors) / (groupsCount - 1)
+	if index >= len(colors) {
+		index = len(colors) - 1
+	}
+
+	return colors[index]
+}
+
+func (r DiscordReporter) GetEmbeds(report Report) []DiscordEmbed {
+	embeds := []DiscordEmbed{}
+
+	for _, alert := range report.MonitoringAlerts {
+		embeds = append(embeds, DiscordEmbed{
+			Description: fmt.Sprintf("%s **%s**", alert.Emoji, EscapeDiscordMarkdown(alert.Description)),
+			Color:       DiscordColorRed,
+		})
+	}
+
+	for _, change := range report.ParamsChanges {
+		embeds = append(embeds, DiscordEmbed{
+			Description: fmt.Sprintf(
+				"%s **%s changed: %s → %s**",
+				ParamChangedEmoji,
+				EscapeDiscordMarkdown(change.Name),
+				EscapeDiscordMarkdown(change.Old),
+				EscapeDiscordMarkdown(change.New),
+			),
+			Color: DiscordColorBlue,
+		})
+	}
+
+	for _, entry := range report.Entries {
+		var sb strings.Builder
+
+		validatorLink := report.ChainInfoConfig.GetValidatorPage(entry.ValidatorAddress, entry.ValidatorMoniker)
+		sb.WriteString(fmt.Sprintf(
+			"%s <strong>%s %s</strong>",
+			entry.Emoji,
+			validatorLink,
+			html.EscapeString(entry.Description),
+		))
+
+		if (entry.Direction == INCREASING || entry.Direction == JAIL_WARNING) && report.Params.AvgBlockTimeKnown() {
+			sb.WriteString(fmt.Sprintf("\n%s", html.EscapeString(entry.GetTimeToJailDesc(&report.Params))))
+		}
+
+		if len(entry.MissedHeights) > 0 {
+			sb.WriteString(fmt.Sprintf(
+				"\nMissed blocks: %s",
+				FormatHeights(entry.MissedHeights, MaxMissedHeightsRanges),
+			))
+		}
+
+		if entry.WhileOffline {
+			sb.WriteString(fmt.Sprintf("\n<i>%s</i>", WhileOfflineDesc))
+		}
+
+		embeds = append(embeds, DiscordEmbed{
+			Description: HTMLToDiscordMarkdown(sb.String()),
+			Color:       GetDiscordColor(entry, report.GroupsCount),
+		})
+	}
+
+	if len(report.FailedValidators) > 0 {
+		embeds = append(embeds, DiscordEmbed{
+			Description: HTMLToDiscordMarkdown(fmt.Sprintf(
+				"%s <i>%s: %s</i>",
+				FailedValidatorsEmoji,
+				FailedValidatorsDesc,
+				report.GetValidatorsPages(report.FailedValidators),
+			)),
+			Color: DiscordColorGrey,
+		})
+	}
+
+	if len(report.RecoveredValidators) > 0 {
+		embeds = append(embeds, DiscordEmbed{
+			Description: HTMLToDiscordMarkdown(fmt.Sprintf(
+				"%s <i>%s: %s</i>",
+				RecoveredValidatorsEmoji,
+				RecoveredValidatorsDesc,
+				report.GetValidatorsPages(report.RecoveredValidators),
+			)),
+			Color: DiscordColorGreen,
+		})
+	}
+
+	for index, embed := range embeds {
+		embeds[index].Description = TruncateString(embed.Description, DiscordMaxEmbedDescriptionLength)
+	}
+
+	return embeds
+}
+
+// GetMessages splits the report embeds into messages fitting into Discord limits,
+// the chain name being the content of the first one.
+func (r DiscordReporter) GetMessages(report Report) []DiscordMessage {
+	messages := []DiscordMessage{}
+	message := DiscordMessage{}
+	messageLength := 0
+
+	if report.ChainName != "" {
+		message.Content = TruncateString(
+			fmt.Sprintf("**%s**", EscapeDiscordMarkdown(report.ChainName)),
+			DiscordMaxContentLength,
+		)
+	}
+
+	for _, embed := range r.GetEmbeds(report) {
+		embedLength := utf8.RuneCountInString(embed.Description)
+		if len(message.Embeds) >= DiscordMaxEmbedsPerMessage ||
+			messageLength+embedLength > DiscordMaxEmbedsLength {
+			messages = append(messages, message)
+			message = DiscordMessage{}
+			messageLength = 0
+		}
+
+		message.Embeds = append(message.Embeds, embed)
+		messageLength += embedLength
+	}
+
+	if len(message.Embeds) > 0 {
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+func (r DiscordReporter) Serialize(report Report) string {
+	var sb strings.Builder
+
+	for _, message := range r.GetMessages(report) {
+		if message.Content != "" {
+			sb.WriteString(message.Content + "\n")
+		}
+
+		for _, embed := range message.Embeds {
+			sb.WriteString(embed.Description + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+func (r *DiscordReporter) Init() {
+	if !r.Enabled() {
+		r.Logger.Debug().Msg("Discord webhook or bot credentials not set, not creating Discord reporter.")
+		return
+	}
+
+	r.Client = &http.Client{Timeout: DiscordRequestTimeout}
+}
+
+func (r DiscordReporter) Enabled() bool {
+	return r.DiscordConfig.WebhookURL != "" ||
+		(r.DiscordConfig.Token != "" && r.DiscordConfig.Channel != "")
+}
+
+func (r DiscordReporter) SendReport(report Report) error {
+	for _, message := range r.GetMessages(report) {
+		if err := r.sendMessage(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendMessage sends a message, waiting and retrying if rate limited.
+func (r DiscordReporter) sendMessage(message DiscordMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	url := r.DiscordConfig.WebhookURL
+	if url == "" {
+		url = fmt.Sprintf("%s/channels/%s/messages", DiscordAPIURL, r.DiscordConfig.Channel)
+	}
+
+	for attempt := 1; ; attempt++ {
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		request.Header.Set("Content-Type", "application/json")
+		if r.DiscordConfig.WebhookURL == "" {
+			request.Header.Set("Authorization", "Bot "+r.DiscordConfig.Token)
+		}
+
+		response, err := r.Client.Do(request)
+		if err != nil {
+			return err
+		}
+
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+
+		if response.StatusCode == http.StatusTooManyRequests && attempt < DiscordMaxAttempts {
+			var rateLimit DiscordRateLimitResponse
+			if err := json.Unmarshal(responseBody, &rateLimit); err != nil || rateLimit.RetryAfter <= 0 {
+				rateLimit.RetryAfter = 1
+			}
+
+			retryIn := time.Duration(rateLimit.RetryAfter * float64(time.Second))
+			r.Logger.Debug().Dur("retryIn", retryIn).Msg("Rate limited by Discord, retrying")
+			time.Sleep(retryIn)
+			continue
+		}
+
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return fmt.Errorf("got status %d: %s", response.StatusCode, responseBody)
+		}
+
+		return nil
+	}
+}
+
+func (r DiscordReporter) Name() string {
+	return "DiscordReporter"
+}
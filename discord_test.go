@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDiscordGetMessages(t *testing.T) {
+	newEntries := func(count int, descriptionLength int) []ReportEntry {
+		entries := make([]ReportEntry, count)
+		for index := range entries {
+			entries[index] = ReportEntry{
+				ValidatorAddress: "validator",
+				ValidatorMoniker: "moniker",
+				Description:      strings.Repeat("a", descriptionLength),
+				Direction:        ACTIVATED,
+			}
+		}
+
+		return entries
+	}
+
+	testCases := []struct {
+		name            string
+		report          Report
+		expectedContent string
+		// Amount of embeds in each of the messages.
+		expectedEmbeds []int
+	}{
+		{
+			name:           "no messages for an empty report",
+			report:         Report{},
+			expectedEmbeds: []int{},
+		},
+		{
+			name: "single message",
+			report: Report{
+				ChainName: "cosmos",
+				Entries:   newEntries(3, 10),
+			},
+			expectedContent: "**cosmos**",
+			expectedEmbeds:  []int{3},
+		},
+		{
+			name: "splits by the amount of embeds",
+			report: Report{
+				ChainName: "cosmos",
+				Entries:   newEntries(25, 10),
+			},
+			expectedContent: "**cosmos**",
+			expectedEmbeds:  []int{10, 10, 5},
+		},
+		{
+			name: "splits by the length of embeds",
+			report: Report{
+				Entries: newEntries(5, 2500),
+			},
+			expectedEmbeds: []int{2, 2, 1},
+		},
+		{
+			name: "escapes the chain name",
+			report: Report{
+				ChainName: "cosmos_hub",
+				Entries:   newEntries(1, 10),
+			},
+			expectedContent: `**cosmos\_hub**`,
+			expectedEmbeds:  []int{1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			messages := DiscordReporter{}.GetMessages(testCase.report)
+
+			embeds := make([]int, len(messages))
+			for index, message := range messages {
+				embeds[index] = len(message.Embeds)
+
+				length := 0
+				for _, embed := range message.Embeds {
+					length += utf8.RuneCountInString(embed.Description)
+				}
+
+				if length > DiscordMaxEmbedsLength {
+					t.Errorf("message %d: embeds are %d characters long", index, length)
+				}
+
+				expectedContent := ""
+				if index == 0 {
+					expectedContent = testCase.expectedContent
+				}
+
+				if message.Content != expectedContent {
+					t.Errorf("message %d: expected content %q, got %q", index, expectedContent, message.Content)
+				}
+			}
+
+			if len(embeds) != len(testCase.expectedEmbeds) {
+				t.Fatalf("expected embeds %v, got %v", testCase.expectedEmbeds, embeds)
+			}
+
+			for index := range embeds {
+				if embeds[index] != testCase.expectedEmbeds[index] {
+					t.Errorf("expected embeds %v, got %v", testCase.expectedEmbeds, embeds)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLToDiscordMarkdown(t *testing.T) {
+	testCases := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"plain text", "is jailed", "is jailed"},
+		{"tags", "<strong>bold</strong> <i>italic</i> <code>code</code>", "**bold** *italic* `code`"},
+		{"escapes markdown", "my_*validator*", `my\_\*validator\*`},
+		{"unescapes HTML", "a &amp; b &lt;c&gt;", "a & b <c>"},
+		{
+			"links",
+			`<a href="https://example.com/validator">my_validator</a>`,
+			`[my\_validator](https://example.com/validator)`,
+		},
+		{
+			"links with brackets",
+			`<a href="https://example.com/(validator)">[validator]</a>`,
+			`[\[validator\]](https://example.com/(validator%29)`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if markdown := HTMLToDiscordMarkdown(testCase.html); markdown != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, markdown)
+			}
+		})
+	}
+}
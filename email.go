@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	EmailSubject = "Missed blocks checker report"
+	EmailTimeout = 30 * time.Second
+)
+
+// EmailReporter sends reports over SMTP as multipart emails with HTML and plain-text bodies.
+// Recipients from To get the whole report, and the ones from ValidatorsRecipients
+// only get the entries about their validators.
+type EmailReporter struct {
+	EmailConfig EmailConfig
+	Logger      zerolog.Logger
+}
+
+type EmailRecipientReport struct {
+	Recipients []string
+	Report     Report
+}
+
+func NewEmailReporter(
+	emailConfig EmailConfig,
+	logger *zerolog.Logger,
+) *EmailReporter {
+	return &EmailReporter{
+		EmailConfig: emailConfig,
+		Logger:      logger.With().Str("component", "email_reporter").Logger(),
+	}
+}
+
+func (r EmailReporter) Serialize(report Report) string {
+	return report.SerializeHTML(nil)
+}
+
+func (r *EmailReporter) Init() {
+	if !r.Enabled() {
+		r.Logger.Debug().Msg("Email host, sender or recipients not set, not creating email reporter.")
+	}
+}
+
+func (r EmailReporter) Enabled() bool {
+	return r.EmailConfig.Host != "" &&
+		r.EmailConfig.From != "" &&
+		(len(r.EmailConfig.To) > 0 || len(r.EmailConfig.ValidatorsRecipients) > 0)
+}
+
+// GetRecipientsReports returns the whole report for To recipients, and for each
+// of the validators recipients the report only having the entries, the failed
+// and the recovered validators about their validators, if there are any.
+func (r EmailReporter) GetRecipientsReports(report Report) []EmailRecipientReport {
+	reports := []EmailRecipientReport{}
+
+	if len(r.EmailConfig.To) > 0 {
+		reports = append(reports, EmailRecipientReport{
+			Recipients: r.EmailConfig.To,
+			Report:     report,
+		})
+	}
+
+	entries := make(map[string][]ReportEntry)
+	failed := make(map[string][]FailedValidator)
+	recovered := make(map[string][]FailedValidator)
+
+	for _, entry := range report.Entries {
+		for _, recipient := range r.EmailConfig.ValidatorsRecipients[entry.ValidatorAddress] {
+			entries[recipient] = append(entries[recipient], entry)
+		}
+	}
+
+	for _, validator := range report.FailedValidators {
+		for _, recipient := range r.EmailConfig.ValidatorsRecipients[validator.Address] {
+			failed[recipient] = append(failed[recipient], validator)
+		}
+	}
+
+	for _, validator := range report.RecoveredValidators {
+		for _, recipient := range r.EmailConfig.ValidatorsRecipients[validator.Address] {
+			recovered[recipient] = append(recovered[recipient], validator)
+		}
+	}
+
+	// Recipients whose validators could only fail to be fetched or be fetched again
+	// should know about it too.
+	recipientsSet := make(map[string]bool)
+	for recipient := range entries {
+		recipientsSet[recipient] = true
+	}
+	for recipient := range failed {
+		recipientsSet[recipient] = true
+	}
+	for recipient := range recovered {
+		recipientsSet[recipient] = true
+	}
+
+	recipients := make([]string, 0, len(recipientsSet))
+	for recipient := range recipientsSet {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+
+	for _, recipient := range recipients {
+		reports = append(reports, EmailRecipientReport{
+			Recipients: []string{recipient},
+			Report: Report{
+				ChainName:        report.ChainName,
+				Time:             report.Time,
+				ChainInfoConfig:  report.ChainInfoConfig,
+				Params:           report.Params,
+				GroupsCount:      report.GroupsCount,
+				Entries:          entries[recipient],
+				FailedValidators: failed[recipient],
+
+				RecoveredValidators: recovered[recipient],
+			},
+		})
+	}
+
+	return reports
+}
+
+func (r EmailReporter) SendReport(report Report) error {
+	failedRecipients := []string{}
+
+	for _, recipientReport := range r.GetRecipientsReports(report) {
+		message, err := r.NewMessage(recipientReport.Recipients, recipientReport.Report)
+		if err == nil {
+			err = r.send(recipientReport.Recipients, message)
+		}
+
+		if err != nil {
+			r.Logger.Error().
+				Err(err).
+				Strs("recipients", recipientReport.Recipients).
+				Msg("Could not send email")
+			failedRecipients = append(failedRecipients, recipientReport.Recipients...)
+		}
+	}
+
+	if len(failedRecipients) > 0 {
+		return fmt.Errorf("could not send email to %s", strings.Join(failedRecipients, ", "))
+	}
+
+	return nil
+}
+
+// NewMessage returns the email with the report as HTML and plain-text alternatives.
+func (r EmailReporter) NewMessage(recipients []string, report Report) ([]byte, error) {
+	serializedReport := r.Serialize(report)
+	htmlBody := fmt.Sprintf(
+		"<html><body>\n%s</body></html>\n",
+		strings.ReplaceAll(serializedReport, "\n", "<br>\n"),
+	)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", HTMLToPlainText(serializedReport)},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	subject := EmailSubject
+	if report.ChainName != "" {
+		subject = fmt.Sprintf("%s: %s", EmailSubject, report.ChainName)
+	}
+
+	var message bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", r.EmailConfig.From},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("UTF-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary())},
+	} {
+		message.WriteString(fmt.Sprintf("%s: %s\r\n", header[0], header[1]))
+	}
+
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func (r EmailReporter) send(recipients []string, message []byte) error {
+	address := net.JoinHostPort(r.EmailConfig.Host, strconv.Itoa(r.EmailConfig.Port))
+	tlsConfig := &tls.Config{ServerName: r.EmailConfig.Host, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: EmailTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if r.EmailConfig.Encryption == EmailEncryptionTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(EmailTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, r.EmailConfig.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	defer client.Close()
+
+	if r.EmailConfig.Encryption == EmailEncryptionSTARTTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if r.EmailConfig.Username != "" {
+		auth := smtp.PlainAuth("", r.EmailConfig.Username, r.EmailConfig.Password, r.EmailConfig.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(r.EmailConfig.From); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (r EmailReporter) Name() string {
+	return "EmailReporter"
+}
@@ -1,4 +1,4 @@
-module main
+module github.com/solarlabsteam/missed-blocks-checker
 
 go 1.18
 
@@ -8,10 +8,12 @@ require (
 	github.com/BurntSushi/toml v1.1.0
 	github.com/cosmos/cosmos-sdk v0.45.4
 	github.com/mcuadros/go-defaults v1.2.0
+	github.com/prometheus/client_golang v1.12.1
 	github.com/rs/zerolog v1.23.0
 	github.com/slack-go/slack v0.9.1
 	github.com/spf13/cobra v1.4.0
 	github.com/tendermint/tendermint v0.34.19
+	go.etcd.io/bbolt v1.3.6
 	google.golang.org/grpc v1.45.0
 	gopkg.in/tucnak/telebot.v2 v2.3.5
 )
@@ -78,7 +80,6 @@ require (
 	github.com/petermattis/goid v0.0.0-20180202154549-b0b1615b78e5 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/prometheus/client_golang v1.12.1 // indirect
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.32.1 // indirect
 	github.com/prometheus/procfs v0.7.3 // indirect
@@ -100,7 +101,6 @@ require (
 	github.com/tendermint/go-amino v0.16.0 // indirect
 	github.com/tendermint/tm-db v0.6.6 // indirect
 	github.com/zondax/hid v0.9.0 // indirect
-	go.etcd.io/bbolt v1.3.6 // indirect
 	golang.org/x/crypto v0.0.0-20210915214749-c084706c2272 // indirect
 	golang.org/x/net v0.0.0-20211208012354-db4efeb81f4b // indirect
 	golang.org/x/sys v0.0.0-20220114195835-da31bd327af9 // indirect
@@ -2,50 +2,172 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
 	querytypes "github.com/cosmos/cosmos-sdk/types/query"
 	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+const GrpcHealthCheckTimeout = 10 * time.Second
+
+// How often to check again whether validators without signing info, like the ones
+// that were never bonded, have it now, when they cannot be matched with the bulk signing infos.
+const NoSigningInfoRecheckInterval = time.Hour
+
 type TendermintGRPC struct {
+	ChainName            string
 	NodeConfig           NodeConfig
 	Limit                uint64
-	Client               *grpc.ClientConn
+	Clients              map[string]*grpc.ClientConn
+	Nodes                *NodesPool
+	Metrics              *Metrics
 	Logger               zerolog.Logger
 	Registry             codectypes.InterfaceRegistry
+	ConsensusNodePrefix  string
 	QueryEachSigningInfo bool
+	SigningInfoWorkers   int
+	SigningInfoTimeout   time.Duration
+	Timeout              time.Duration
+
+	// When the validators were found to have no signing info, keyed by consensus address.
+	// Only accessed from the chain loop.
+	noSigningInfo map[string]time.Time
 }
 
 func NewTendermintGRPC(
-	nodeConfig NodeConfig,
+	chainConfig *ChainConfig,
+	metrics *Metrics,
 	registry codectypes.InterfaceRegistry,
-	queryEachSigningInfo bool,
 	logger *zerolog.Logger,
 ) *TendermintGRPC {
-	grpcConn, err := grpc.Dial(
-		nodeConfig.GrpcAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-	if err != nil {
-		GetDefaultLogger().Fatal().Err(err).Msg("Could not establish gRPC connection")
+	addresses := chainConfig.NodeConfig.GetGrpcAddresses()
+	clients := make(map[string]*grpc.ClientConn, len(addresses))
+
+	for _, address := range addresses {
+		grpcConn, err := grpc.Dial(
+			address,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			GetDefaultLogger().Fatal().Err(err).Str("address", address).Msg("Could not establish gRPC connection")
+		}
+
+		clients[address] = grpcConn
 	}
 
-	return &TendermintGRPC{
-		NodeConfig:           nodeConfig,
-		Limit:                1000,
+	tendermintGRPC := &TendermintGRPC{
+		ChainName:            chainConfig.GetName(),
+		NodeConfig:           chainConfig.NodeConfig,
+		Limit:                chainConfig.NodeConfig.PageSize,
 		Logger:               logger.With().Str("component", "grpc").Logger(),
-		Client:               grpcConn,
+		Clients:              clients,
+		Metrics:              metrics,
 		Registry:             registry,
-		QueryEachSigningInfo: queryEachSigningInfo,
+		ConsensusNodePrefix:  chainConfig.ConsensusNodePrefix,
+		QueryEachSigningInfo: chainConfig.QueryEachSigningInfo,
+		SigningInfoWorkers:   chainConfig.SigningInfoWorkers,
+		SigningInfoTimeout:   time.Duration(chainConfig.SigningInfoTimeout) * time.Second,
+		Timeout:              time.Duration(chainConfig.NodeConfig.Timeout) * time.Second,
+		noSigningInfo:        make(map[string]time.Time),
+	}
+
+	tendermintGRPC.Nodes = NewNodesPool(
+		addresses,
+		chainConfig.NodeConfig.FailoverPolicy,
+		tendermintGRPC.CheckNode,
+		logger,
+	)
+
+	return tendermintGRPC
+}
+
+// CheckNode returns an error if the node is unreachable or is still syncing.
+func (grpc *TendermintGRPC) CheckNode(address string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), GrpcHealthCheckTimeout)
+	defer cancel()
+
+	serviceClient := tmservice.NewServiceClient(grpc.Clients[address])
+	response, err := serviceClient.GetSyncing(ctx, &tmservice.GetSyncingRequest{})
+	if err != nil {
+		return err
+	}
+
+	if response.Syncing {
+		return fmt.Errorf("node is syncing")
+	}
+
+	return nil
+}
+
+// Query runs the query against the nodes in the order given by the nodes pool,
+// passing it the address of the node to use, and fails over to the next node
+// if the node is unavailable or doesn't answer within Timeout. Other errors,
+// like the requested object not existing, are returned as is.
+func (grpc *TendermintGRPC) Query(query func(ctx context.Context, address string) error) error {
+	return grpc.QueryWithTimeout(grpc.Timeout, query)
+}
+
+// QueryWithTimeout is Query giving each node the given time to answer.
+func (grpc *TendermintGRPC) QueryWithTimeout(
+	timeout time.Duration,
+	query func(ctx context.Context, address string) error,
+) error {
+	var err error
+
+	for _, address := range grpc.Nodes.GetNodes() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = query(ctx, address)
+		cancel()
+
+		// The requested object not existing is a valid answer, not a failed query.
+		if err != nil && status.Code(err) != codes.NotFound {
+			grpc.Metrics.IncGrpcErrors(grpc.ChainName)
+		}
+
+		if !IsGrpcNodeError(err) {
+			return err
+		}
+
+		grpc.Nodes.SetHealthy(address, err)
+	}
+
+	return err
+}
+
+// IsGrpcNodeError checks whether the error is caused by the node itself
+// and the query may succeed on another node.
+func IsGrpcNodeError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
 	}
 }
 
+// GetConsAddress returns the validator's consensus address with this chain's prefix.
+// Not using sdk.ConsAddress.String() as it depends on the global SDK config,
+// which cannot hold prefixes for multiple chains.
+func (grpc *TendermintGRPC) GetConsAddress(validator stakingtypes.Validator) (string, error) {
+	consAddr, err := validator.GetConsAddr()
+	if err != nil {
+		return "", err
+	}
+
+	return bech32.ConvertAndEncode(grpc.ConsensusNodePrefix, consAddr)
+}
+
 type SlashingParams struct {
 	SignedBlocksWindow      int64
 	MinSignedPerWindow      float64
@@ -55,14 +177,20 @@ type SlashingParams struct {
 	SlashFractionDowntime   float64
 }
 
-func (grpc *TendermintGRPC) GetSlashingParams() SlashingParams {
-	slashingClient := slashingtypes.NewQueryClient(grpc.Client)
-	params, err := slashingClient.Params(
-		context.Background(),
-		&slashingtypes.QueryParamsRequest{},
-	)
+func (grpc *TendermintGRPC) GetSlashingParams() (SlashingParams, error) {
+	var params *slashingtypes.QueryParamsResponse
+
+	err := grpc.Query(func(ctx context.Context, address string) error {
+		var err error
+		params, err = slashingtypes.NewQueryClient(grpc.Clients[address]).Params(
+			ctx,
+			&slashingtypes.QueryParamsRequest{},
+		)
+		return err
+	})
 	if err != nil {
-		grpc.Logger.Fatal().Err(err).Msg("Could not query slashing params")
+		grpc.Logger.Error().Err(err).Msg("Could not query slashing params")
+		return SlashingParams{}, err
 	}
 
 	minSignedPerWindow := params.Params.MinSignedPerWindow.MustFloat64()
@@ -74,128 +202,316 @@ func (grpc *TendermintGRPC) GetSlashingParams() SlashingParams {
 		DowntimeJailDuration:    params.Params.DowntimeJailDuration,
 		SlashFractionDoubleSign: params.Params.SlashFractionDoubleSign.MustFloat64(),
 		SlashFractionDowntime:   params.Params.SlashFractionDowntime.MustFloat64(),
+	}, nil
+}
+
+// GetAllValidators pages through all the validators, as a node might return only
+// a part of them in a single response.
+func (grpc *TendermintGRPC) GetAllValidators() ([]stakingtypes.Validator, error) {
+	validators := []stakingtypes.Validator{}
+
+	var nextKey []byte
+
+	for {
+		var validatorsResult *stakingtypes.QueryValidatorsResponse
+
+		err := grpc.Query(func(ctx context.Context, address string) error {
+			var err error
+			validatorsResult, err = stakingtypes.NewQueryClient(grpc.Clients[address]).Validators(
+				ctx,
+				&stakingtypes.QueryValidatorsRequest{
+					Pagination: &querytypes.PageRequest{
+						Key:   nextKey,
+						Limit: grpc.Limit,
+					},
+				},
+			)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		validators = append(validators, validatorsResult.Validators...)
+
+		if validatorsResult.Pagination == nil || len(validatorsResult.Pagination.NextKey) == 0 {
+			break
+		}
+
+		nextKey = validatorsResult.Pagination.NextKey
+	}
+
+	return validators, nil
+}
+
+// GetAllSigningInfos pages through all the signing infos, as a node might return only
+// a part of them in a single response.
+func (grpc *TendermintGRPC) GetAllSigningInfos() ([]slashingtypes.ValidatorSigningInfo, error) {
+	signingInfos := []slashingtypes.ValidatorSigningInfo{}
+
+	var nextKey []byte
+
+	for {
+		var signingInfosResult *slashingtypes.QuerySigningInfosResponse
+
+		err := grpc.Query(func(ctx context.Context, address string) error {
+			var err error
+			signingInfosResult, err = slashingtypes.NewQueryClient(grpc.Clients[address]).SigningInfos(
+				ctx,
+				&slashingtypes.QuerySigningInfosRequest{
+					Pagination: &querytypes.PageRequest{
+						Key:   nextKey,
+						Limit: grpc.Limit,
+					},
+				},
+			)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		signingInfos = append(signingInfos, signingInfosResult.Info...)
+
+		if signingInfosResult.Pagination == nil || len(signingInfosResult.Pagination.NextKey) == 0 {
+			break
+		}
+
+		nextKey = signingInfosResult.Pagination.NextKey
 	}
+
+	return signingInfos, nil
 }
 
-func (grpc *TendermintGRPC) GetValidatorsState() (ValidatorsState, error) {
+// GetValidatorsState returns the state of all the validators that were fetched successfully,
+// and the list of validators which signing info could not be fetched.
+func (grpc *TendermintGRPC) GetValidatorsState() (ValidatorsState, []FailedValidator, error) {
 	if grpc.QueryEachSigningInfo {
 		return grpc.GetValidatorsStateWithEachSigningInfo()
 	}
 
-	slashingClient := slashingtypes.NewQueryClient(grpc.Client)
-	signingInfos, err := slashingClient.SigningInfos(
-		context.Background(),
-		&slashingtypes.QuerySigningInfosRequest{
-			Pagination: &querytypes.PageRequest{
-				Limit: grpc.Limit,
-			},
-		},
-	)
+	signingInfos, err := grpc.GetAllSigningInfos()
 	if err != nil {
 		grpc.Logger.Error().Err(err).Msg("Could not query for signing info")
-		return nil, err
+		return nil, nil, err
 	}
 
-	stakingClient := stakingtypes.NewQueryClient(grpc.Client)
-	validatorsResult, err := stakingClient.Validators(
-		context.Background(),
-		&stakingtypes.QueryValidatorsRequest{
-			Pagination: &querytypes.PageRequest{
-				Limit: grpc.Limit,
-			},
-		},
-	)
+	validators, err := grpc.GetAllValidators()
 	if err != nil {
 		grpc.Logger.Error().Err(err).Msg("Could not query for validators")
-		return nil, err
+		return nil, nil, err
 	}
 
-	validatorsMap := make(map[string]stakingtypes.Validator, len(validatorsResult.Validators))
-	for _, validator := range validatorsResult.Validators {
+	validatorsMap := make(map[string]stakingtypes.Validator, len(validators))
+	for _, validator := range validators {
 		err := validator.UnpackInterfaces(grpc.Registry)
 		if err != nil {
 			grpc.Logger.Error().Err(err).Msg("Could not unpack interface")
-			return nil, err
+			return nil, nil, err
 		}
 
-		pubKey, err := validator.GetConsAddr()
+		consAddress, err := grpc.GetConsAddress(validator)
 		if err != nil {
 			grpc.Logger.Error().Err(err).Msg("Could not get cons addr")
-			return nil, err
+			return nil, nil, err
 		}
 
-		validatorsMap[pubKey.String()] = validator
+		validatorsMap[consAddress] = validator
 	}
 
-	newState := make(ValidatorsState, len(signingInfos.Info))
+	newState := make(ValidatorsState, len(signingInfos))
 
-	for _, info := range signingInfos.Info {
+	for _, info := range signingInfos {
 		validator, ok := validatorsMap[info.Address]
 		if !ok {
-			grpc.Logger.Warn().Str("address", info.Address).Msg("Could not find validator by pubkey")
+			grpc.Logger.Debug().Str("address", info.Address).Msg("Could not find validator by pubkey")
 			continue
 		}
 
 		newState[info.Address] = NewValidatorState(validator, info)
 	}
 
-	return newState, nil
+	matched := len(newState)
+
+	// Some chains return signing infos without an address, so some validators cannot
+	// be matched with their signing info. Querying for these separately, except for the ones
+	// recently found to have no signing info at all, so the validators that were never bonded
+	// are not queried for each poll.
+	unmatchedValidators := make(map[string]stakingtypes.Validator)
+	for consAddress, validator := range validatorsMap {
+		if _, ok := newState[consAddress]; ok {
+			continue
+		}
+
+		if checkedAt, ok := grpc.noSigningInfo[consAddress]; ok && time.Since(checkedAt) < NoSigningInfoRecheckInterval {
+			continue
+		}
+
+		unmatchedValidators[consAddress] = validator
+	}
+
+	failed := []FailedValidator{}
+
+	if len(unmatchedValidators) > 0 {
+		grpc.Logger.Debug().
+			Int("count", len(unmatchedValidators)).
+			Msg("Some validators have no signing info matched, querying for them separately")
+
+		var reconciledState ValidatorsState
+		reconciledState, failed = grpc.GetEachSigningInfo(unmatchedValidators)
+
+		for consAddress, state := range reconciledState {
+			newState[consAddress] = state
+		}
+	}
+
+	grpc.Logger.Info().
+		Int("validators", len(validators)).
+		Int("signingInfos", len(signingInfos)).
+		Int("matched", matched).
+		Int("reconciled", len(newState)-matched).
+		Int("failed", len(failed)).
+		Msg("Fetched validators and signing infos")
+
+	return newState, failed, nil
 }
 
-func (grpc *TendermintGRPC) GetValidatorsStateWithEachSigningInfo() (ValidatorsState, error) {
-	slashingClient := slashingtypes.NewQueryClient(grpc.Client)
-	stakingClient := stakingtypes.NewQueryClient(grpc.Client)
-	validatorsResult, err := stakingClient.Validators(
-		context.Background(),
-		&stakingtypes.QueryValidatorsRequest{
-			Pagination: &querytypes.PageRequest{
-				Limit: grpc.Limit,
-			},
-		},
-	)
+func (grpc *TendermintGRPC) GetValidatorsStateWithEachSigningInfo() (ValidatorsState, []FailedValidator, error) {
+	validators, err := grpc.GetAllValidators()
 	if err != nil {
 		grpc.Logger.Error().Err(err).Msg("Could not query for validators")
-		return nil, err
+		return nil, nil, err
 	}
 
-	newState := make(ValidatorsState, len(validatorsResult.Validators))
-	for _, validator := range validatorsResult.Validators {
+	validatorsMap := make(map[string]stakingtypes.Validator, len(validators))
+	for _, validator := range validators {
 		err := validator.UnpackInterfaces(grpc.Registry)
 		if err != nil {
 			grpc.Logger.Error().Err(err).Msg("Could not unpack interface")
-			return nil, err
+			return nil, nil, err
 		}
 
-		pubKey, err := validator.GetConsAddr()
+		consAddress, err := grpc.GetConsAddress(validator)
 		if err != nil {
 			grpc.Logger.Error().Err(err).Msg("Could not get cons addr")
-			return nil, err
+			return nil, nil, err
 		}
 
-		info, err := slashingClient.SigningInfo(
-			context.Background(),
-			&slashingtypes.QuerySigningInfoRequest{
-				ConsAddress: pubKey.String(),
-			},
-		)
-		if err != nil {
-			grpc.Logger.Error().Err(err).Msg("Could not query for signing info")
+		validatorsMap[consAddress] = validator
+	}
+
+	newState, failed := grpc.GetEachSigningInfo(validatorsMap)
+
+	grpc.Logger.Info().
+		Int("validators", len(validators)).
+		Int("signingInfos", len(newState)).
+		Int("failed", len(failed)).
+		Msg("Fetched validators and signing infos")
+
+	return newState, failed, nil
+}
+
+// GetEachSigningInfo queries for the signing info of each validator from the map keyed
+// by consensus address, doing no more than SigningInfoWorkers requests at the same time.
+// Returns the state of validators whose signing info was fetched and the list of the ones
+// that failed.
+func (grpc *TendermintGRPC) GetEachSigningInfo(
+	validatorsMap map[string]stakingtypes.Validator,
+) (ValidatorsState, []FailedValidator) {
+	type signingInfoResult struct {
+		consAddress string
+		info        slashingtypes.ValidatorSigningInfo
+		err         error
+	}
+
+	consAddresses := make(chan string)
+	results := make(chan signingInfoResult)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < grpc.SigningInfoWorkers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for consAddress := range consAddresses {
+				result := signingInfoResult{consAddress: consAddress}
+
+				result.err = grpc.QueryWithTimeout(grpc.SigningInfoTimeout, func(ctx context.Context, address string) error {
+					info, err := slashingtypes.NewQueryClient(grpc.Clients[address]).SigningInfo(
+						ctx,
+						&slashingtypes.QuerySigningInfoRequest{ConsAddress: consAddress},
+					)
+					if err == nil {
+						result.info = info.ValSigningInfo
+					}
+					return err
+				})
+
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		for consAddress := range validatorsMap {
+			consAddresses <- consAddress
+		}
+
+		close(consAddresses)
+		wg.Wait()
+		close(results)
+	}()
+
+	newState := make(ValidatorsState, len(validatorsMap))
+	failed := []FailedValidator{}
+
+	for result := range results {
+		validator := validatorsMap[result.consAddress]
+
+		// Validators that were never bonded have no signing info yet.
+		if status.Code(result.err) == codes.NotFound {
+			grpc.Logger.Debug().
+				Str("address", validator.OperatorAddress).
+				Msg("Validator has no signing info yet, skipping")
+			grpc.noSigningInfo[result.consAddress] = time.Now()
 			continue
 		}
 
-		newState[pubKey.String()] = NewValidatorState(validator, info.ValSigningInfo)
+		delete(grpc.noSigningInfo, result.consAddress)
+
+		if result.err != nil {
+			grpc.Logger.Error().
+				Err(result.err).
+				Str("address", validator.OperatorAddress).
+				Msg("Could not query for signing info")
+			failed = append(failed, FailedValidator{
+				Address:          validator.OperatorAddress,
+				Moniker:          validator.Description.Moniker,
+				ConsensusAddress: result.consAddress,
+				Error:            result.err.Error(),
+			})
+			continue
+		}
+
+		newState[result.consAddress] = NewValidatorState(validator, result.info)
 	}
 
-	return newState, nil
+	return newState, failed
 }
 
 func (grpc *TendermintGRPC) GetValidator(address string) (stakingtypes.Validator, error) {
-	stakingClient := stakingtypes.NewQueryClient(grpc.Client)
+	var validatorResponse *stakingtypes.QueryValidatorResponse
 
-	validatorResponse, err := stakingClient.Validator(
-		context.Background(),
-		&stakingtypes.QueryValidatorRequest{ValidatorAddr: address},
-	)
+	err := grpc.Query(func(ctx context.Context, nodeAddress string) error {
+		var err error
+		validatorResponse, err = stakingtypes.NewQueryClient(grpc.Clients[nodeAddress]).Validator(
+			ctx,
+			&stakingtypes.QueryValidatorRequest{ValidatorAddr: address},
+		)
+		return err
+	})
 	if err != nil {
 		return stakingtypes.Validator{}, err
 	}
@@ -204,19 +520,11 @@ func (grpc *TendermintGRPC) GetValidator(address string) (stakingtypes.Validator
 }
 
 func (grpc *TendermintGRPC) GetValidatorState(address string) (ValidatorState, error) {
-	stakingClient := stakingtypes.NewQueryClient(grpc.Client)
-
-	validatorResponse, err := stakingClient.Validator(
-		context.Background(),
-		&stakingtypes.QueryValidatorRequest{ValidatorAddr: address},
-	)
+	validator, err := grpc.GetValidator(address)
 	if err != nil {
 		return ValidatorState{}, err
 	}
 
-	validator := validatorResponse.Validator
-	slashingClient := slashingtypes.NewQueryClient(grpc.Client)
-
 	err = validator.UnpackInterfaces(grpc.Registry) // Unpack interfaces, to populate the Anys' cached values
 	if err != nil {
 		grpc.Logger.Error().
@@ -226,7 +534,7 @@ func (grpc *TendermintGRPC) GetValidatorState(address string) (ValidatorState, e
 		return ValidatorState{}, err
 	}
 
-	pubKey, err := validator.GetConsAddr()
+	consAddress, err := grpc.GetConsAddress(validator)
 	if err != nil {
 		grpc.Logger.Error().
 			Str("address", validator.OperatorAddress).
@@ -235,10 +543,16 @@ func (grpc *TendermintGRPC) GetValidatorState(address string) (ValidatorState, e
 		return ValidatorState{}, err
 	}
 
-	signingInfosResponse, err := slashingClient.SigningInfo(
-		context.Background(),
-		&slashingtypes.QuerySigningInfoRequest{ConsAddress: pubKey.String()},
-	)
+	var signingInfosResponse *slashingtypes.QuerySigningInfoResponse
+
+	err = grpc.Query(func(ctx context.Context, address string) error {
+		var err error
+		signingInfosResponse, err = slashingtypes.NewQueryClient(grpc.Clients[address]).SigningInfo(
+			ctx,
+			&slashingtypes.QuerySigningInfoRequest{ConsAddress: consAddress},
+		)
+		return err
+	})
 	if err != nil {
 		grpc.Logger.Error().
 			Str("address", validator.OperatorAddress).
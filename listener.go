@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	tmrpc "github.com/tendermint/tendermint/rpc/client/http"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const NewBlocksSubscriber = "missed-blocks-checker"
+
+// NewBlocksListener subscribes to NewBlock events via Tendermint websocket and notifies
+// the main loop every BlocksInterval blocks, so reports are generated as the chain progresses
+// and not by wall clock. If the subscription is lost, it resubscribes in the background,
+// while Wait falls back to polling every Interval. Each subscription goes
+// to the node the nodes pool considers the best at the moment.
+type NewBlocksListener struct {
+	Nodes          *NodesPool
+	BlocksInterval int64
+	Interval       time.Duration
+	Logger         zerolog.Logger
+
+	Trigger    chan struct{}
+	subscribed int32
+}
+
+func NewNewBlocksListener(
+	nodes *NodesPool,
+	blocksInterval int64,
+	interval time.Duration,
+	logger *zerolog.Logger,
+) *NewBlocksListener {
+	return &NewBlocksListener{
+		Nodes:          nodes,
+		BlocksInterval: blocksInterval,
+		Interval:       interval,
+		Logger:         logger.With().Str("component", "new_blocks_listener").Logger(),
+		Trigger:        make(chan struct{}, 1),
+	}
+}
+
+func (l *NewBlocksListener) Subscribed() bool {
+	return atomic.LoadInt32(&l.subscribed) == 1
+}
+
+// Listen keeps the subscription alive, resubscribing if it's lost. Never returns.
+func (l *NewBlocksListener) Listen() {
+	for {
+		if err := l.listen(); err != nil {
+			l.Logger.Error().Err(err).Msg("New blocks subscription failed")
+		}
+
+		atomic.StoreInt32(&l.subscribed, 0)
+		l.Logger.Info().
+			Dur("retryIn", l.Interval).
+			Msg("Not subscribed to new blocks, falling back to polling")
+		time.Sleep(l.Interval)
+	}
+}
+
+func (l *NewBlocksListener) listen() error {
+	address := l.Nodes.GetNode()
+
+	client, err := tmrpc.New(address, "/websocket")
+	if err != nil {
+		return err
+	}
+
+	if err := client.Start(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := client.Stop(); err != nil {
+			l.Logger.Warn().Err(err).Msg("Could not stop Tendermint client")
+		}
+	}()
+
+	events, err := client.Subscribe(
+		context.Background(),
+		NewBlocksSubscriber,
+		tmtypes.EventQueryNewBlock.String(),
+	)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&l.subscribed, 1)
+	l.Logger.Info().
+		Str("address", address).
+		Int64("blocksInterval", l.BlocksInterval).
+		Msg("Subscribed to new blocks")
+
+	var blocksSinceTrigger int64
+
+	for {
+		select {
+		case event := <-events:
+			data, ok := event.Data.(tmtypes.EventDataNewBlock)
+			if !ok {
+				l.Logger.Warn().Str("query", event.Query).Msg("Got unexpected event, skipping")
+				continue
+			}
+
+			blocksSinceTrigger++
+			l.Logger.Trace().Int64("height", data.Block.Height).Msg("Got new block")
+
+			if blocksSinceTrigger < l.BlocksInterval {
+				continue
+			}
+
+			blocksSinceTrigger = 0
+
+			select {
+			case l.Trigger <- struct{}{}:
+			default:
+				l.Logger.Debug().
+					Int64("height", data.Block.Height).
+					Msg("Previous report is still being generated, skipping")
+			}
+		// The channel is never closed by Tendermint client, so the only way to know
+		// the connection is lost is not receiving blocks for a while.
+		case <-time.After(l.Interval):
+			return fmt.Errorf("no new blocks received for %s", l.Interval)
+		}
+	}
+}
+
+// Wait blocks until it's time to generate the next report: either enough new blocks
+// were produced, or, if not subscribed to new blocks, Interval has passed.
+func (l *NewBlocksListener) Wait() {
+	for {
+		select {
+		case <-l.Trigger:
+			return
+		case <-time.After(l.Interval):
+			if !l.Subscribed() {
+				return
+			}
+		}
+	}
+}
@@ -2,17 +2,26 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/simapp"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/spf13/cobra"
 )
 
 type Params struct {
-	AvgBlockTime       float64
-	SignedBlocksWindow int64
-	MissedBlocksToJail int64
+	AvgBlockTime float64
+	// Amount of blocks AvgBlockTime is estimated from, 0 if it's not known yet.
+	AvgBlockTimeSamples  int64
+	SignedBlocksWindow   int64
+	MissedBlocksToJail   int64
+	DowntimeJailDuration time.Duration
+}
+
+func (p *Params) AvgBlockTimeKnown() bool {
+	return p.AvgBlockTimeSamples > 0
 }
 
 func Execute(configPath string) {
@@ -23,52 +32,41 @@ func Execute(configPath string) {
 
 	appConfig.Validate()        // will exit if not valid
 	appConfig.SetBechPrefixes() // will exit if not valid
-	SetSdkConfigPrefixes(appConfig)
 
 	log := GetLogger(appConfig.LogConfig)
 
-	if len(appConfig.IncludeValidators) == 0 && len(appConfig.ExcludeValidators) == 0 {
-		log.Info().Msg("Monitoring all validators")
-	} else if len(appConfig.IncludeValidators) != 0 {
-		log.Info().
-			Strs("validators", appConfig.IncludeValidators).
-			Msg("Monitoring specific validators")
-	} else {
-		log.Info().
-			Strs("validators", appConfig.ExcludeValidators).
-			Msg("Monitoring all validators except specific")
-	}
-
 	encCfg := simapp.MakeTestEncodingConfig()
 	interfaceRegistry := encCfg.InterfaceRegistry
 
-	rpc := NewTendermintRPC(appConfig.NodeConfig, log)
-	grpc := NewTendermintGRPC(appConfig.NodeConfig, interfaceRegistry, appConfig.QueryEachSigningInfo, log)
-	slashingParams := grpc.GetSlashingParams()
-
-	params := Params{
-		AvgBlockTime:       rpc.GetAvgBlockTime(),
-		SignedBlocksWindow: slashingParams.SignedBlocksWindow,
-		MissedBlocksToJail: slashingParams.MissedBlocksToJail,
+	database := NewDatabase(appConfig.DatabaseConfig, log)
+	if err := database.Init(); err != nil {
+		log.Fatal().Err(err).Msg("Could not open database")
 	}
+	defer database.Close()
 
-	log.Info().
-		Int64("missedBlocksToJail", params.MissedBlocksToJail).
-		Float64("avgBlockTime", params.AvgBlockTime).
-		Msg("Chain params calculated")
+	go database.StartPruning()
+
+	metrics := NewMetrics(appConfig.MetricsConfig, log)
+	go metrics.Start()
 
-	appConfig.SetDefaultMissedBlocksGroups(params)
-	if err := appConfig.MissedBlocksGroups.Validate(params.SignedBlocksWindow); err != nil {
-		log.Fatal().Err(err).Msg("MissedBlockGroups config is invalid")
+	chainsConfigs := appConfig.GetChains()
+	chains := make([]*Chain, len(chainsConfigs))
+	for index, chainConfig := range chainsConfigs {
+		chains[index] = NewChain(chainConfig, database, metrics, interfaceRegistry, log)
 	}
 
 	log.Info().
-		Str("config", fmt.Sprintf("%+v", appConfig)).
+		Str("config", fmt.Sprintf("%+v", appConfig.Redacted())).
 		Msg("Started with following parameters")
 
 	reporters := []Reporter{
-		NewTelegramReporter(appConfig.ChainInfoConfig, appConfig.TelegramConfig, appConfig, &params, grpc, log),
-		NewSlackReporter(appConfig.ChainInfoConfig, appConfig.SlackConfig, &params, log),
+		NewTelegramReporter(appConfig.TelegramConfig, chains, log),
+		NewSlackReporter(appConfig.SlackConfig, log),
+		NewWebhookReporter(appConfig.WebhookConfig, log),
+		NewDiscordReporter(appConfig.DiscordConfig, log),
+		NewPagerDutyReporter(appConfig.PagerDutyConfig, log),
+		NewAlertmanagerReporter(appConfig.AlertmanagerConfig, chains, log),
+		NewEmailReporter(appConfig.EmailConfig, log),
 	}
 
 	for _, reporter := range reporters {
@@ -79,37 +77,29 @@ func Execute(configPath string) {
 		}
 	}
 
-	reportGenerator := NewReportGenerator(params, grpc, appConfig, log, interfaceRegistry)
+	for _, chain := range chains {
+		go chain.Start(reporters)
+	}
 
-	for {
-		report := reportGenerator.GenerateReport()
-		if report == nil || len(report.Entries) == 0 {
-			log.Info().Msg("Report is empty, not sending.")
-			time.Sleep(time.Duration(appConfig.Interval) * time.Second)
-			continue
-		}
+	api := NewAPI(appConfig.APIConfig, chains, database, log)
+	go api.Start()
 
-		for _, reporter := range reporters {
-			if !reporter.Enabled() {
-				log.Debug().Str("name", reporter.Name()).Msg("Reporter is disabled.")
-				continue
-			}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
-			log.Info().Str("name", reporter.Name()).Msg("Sending a report to reporter...")
-			if err := reporter.SendReport(*report); err != nil {
-				log.Error().Err(err).Str("name", reporter.Name()).Msg("Could not send message")
-			}
-		}
+	sig := <-signals
+	log.Info().Str("signal", sig.String()).Msg("Got signal, shutting down")
 
-		time.Sleep(time.Duration(appConfig.Interval) * time.Second)
-	}
-}
+	// A second signal exits right away if a poll in progress takes too long to finish.
+	go func() {
+		<-signals
+		log.Warn().Msg("Got second signal, exiting without saving state")
+		os.Exit(1)
+	}()
 
-func SetSdkConfigPrefixes(appConfig *AppConfig) {
-	config := sdk.GetConfig()
-	config.SetBech32PrefixForValidator(appConfig.ValidatorPrefix, appConfig.ValidatorPubkeyPrefix)
-	config.SetBech32PrefixForConsensusNode(appConfig.ConsensusNodePrefix, appConfig.ConsensusNodePubkeyPrefix)
-	config.Seal()
+	for _, chain := range chains {
+		chain.Stop()
+	}
 }
 
 func main() {
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+const MetricsNamespace = "missed_blocks_checker"
+
+// Metrics exposes the validators state fetched every poll and the checker internals
+// in Prometheus format. All the methods do nothing if metrics are disabled.
+type Metrics struct {
+	Config   MetricsConfig
+	Logger   zerolog.Logger
+	Registry *prometheus.Registry
+
+	validatorMissedBlocks      *prometheus.GaugeVec
+	validatorMissedBlocksRatio *prometheus.GaugeVec
+	validatorJailed            *prometheus.GaugeVec
+	validatorTombstoned        *prometheus.GaugeVec
+	validatorActive            *prometheus.GaugeVec
+	validatorTimeToJail        *prometheus.GaugeVec
+
+	pollDuration     *prometheus.HistogramVec
+	pollErrors       *prometheus.CounterVec
+	grpcErrors       *prometheus.CounterVec
+	reporterFailures *prometheus.CounterVec
+
+	avgBlockTime         *prometheus.GaugeVec
+	signedBlocksWindow   *prometheus.GaugeVec
+	missedBlocksToJail   *prometheus.GaugeVec
+	downtimeJailDuration *prometheus.GaugeVec
+
+	// Validators labels set for each chain, to remove the ones not present anymore.
+	mutex           sync.Mutex
+	validatorLabels map[string]map[string]prometheus.Labels
+}
+
+func NewMetrics(config MetricsConfig, logger *zerolog.Logger) *Metrics {
+	validatorLabels := []string{"chain", "address", "moniker"}
+
+	newValidatorGauge := func(name, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      name,
+			Help:      help,
+		}, validatorLabels)
+	}
+
+	newChainGauge := func(name, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      name,
+			Help:      help,
+		}, []string{"chain"})
+	}
+
+	m := &Metrics{
+		Config:   config,
+		Logger:   logger.With().Str("component", "metrics").Logger(),
+		Registry: prometheus.NewRegistry(),
+
+		validatorMissedBlocks: newValidatorGauge(
+			"validator_missed_blocks",
+			"Blocks missed by the validator in the signed blocks window",
+		),
+		validatorMissedBlocksRatio: newValidatorGauge(
+			"validator_missed_blocks_ratio",
+			"Share of blocks missed by the validator in the signed blocks window",
+		),
+		validatorJailed:     newValidatorGauge("validator_jailed", "Whether the validator is jailed"),
+		validatorTombstoned: newValidatorGauge("validator_tombstoned", "Whether the validator is tombstoned"),
+		validatorActive:     newValidatorGauge("validator_active", "Whether the validator is in the active set"),
+		validatorTimeToJail: newValidatorGauge(
+			"validator_time_to_jail_seconds",
+			"Estimated time till the validator is jailed if it misses all the remaining blocks",
+		),
+
+		pollDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "poll_duration_seconds",
+			Help:      "Time taken to fetch the validators state and generate a report",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+		}, []string{"chain"}),
+		pollErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "poll_errors_total",
+			Help:      "Polls that failed to fetch the validators state",
+		}, []string{"chain"}),
+		grpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "grpc_errors_total",
+			Help:      "Failed gRPC queries, including the ones retried on another node",
+		}, []string{"chain"}),
+		reporterFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "reporter_send_failures_total",
+			Help:      "Reports that could not be sent",
+		}, []string{"chain", "reporter"}),
+
+		avgBlockTime:         newChainGauge("avg_block_time_seconds", "Estimated average block time"),
+		signedBlocksWindow:   newChainGauge("signed_blocks_window", "Signed blocks window slashing param"),
+		missedBlocksToJail:   newChainGauge("missed_blocks_to_jail", "Blocks a validator needs to miss to be jailed"),
+		downtimeJailDuration: newChainGauge("downtime_jail_duration_seconds", "Downtime jail duration slashing param"),
+
+		validatorLabels: make(map[string]map[string]prometheus.Labels),
+	}
+
+	m.Registry.MustRegister(
+		m.validatorMissedBlocks,
+		m.validatorMissedBlocksRatio,
+		m.validatorJailed,
+		m.validatorTombstoned,
+		m.validatorActive,
+		m.validatorTimeToJail,
+		m.pollDuration,
+		m.pollErrors,
+		m.grpcErrors,
+		m.reporterFailures,
+		m.avgBlockTime,
+		m.signedBlocksWindow,
+		m.missedBlocksToJail,
+		m.downtimeJailDuration,
+	)
+
+	return m
+}
+
+func (m *Metrics) Enabled() bool {
+	return m.Config.ListenAddress != ""
+}
+
+// Start serves metrics on the configured address. Never returns if enabled.
+func (m *Metrics) Start() {
+	if !m.Enabled() {
+		m.Logger.Debug().Msg("Metrics listen address not set, not starting metrics server.")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{}))
+
+	m.Logger.Info().Str("address", m.Config.ListenAddress).Msg("Serving metrics")
+	if err := http.ListenAndServe(m.Config.ListenAddress, mux); err != nil {
+		m.Logger.Fatal().Err(err).Msg("Could not start metrics server")
+	}
+}
+
+func (m *Metrics) SetValidatorsState(chain string, state ValidatorsState, params Params) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	newLabels := make(map[string]prometheus.Labels, len(state))
+
+	for _, validator := range state {
+		labels := prometheus.Labels{
+			"chain":   chain,
+			"address": validator.Address,
+			"moniker": validator.Moniker,
+		}
+		newLabels[validator.Address] = labels
+
+		m.validatorMissedBlocks.With(labels).Set(float64(validator.MissedBlocks))
+		m.validatorJailed.With(labels).Set(BoolToFloat64(validator.Jailed))
+		m.validatorTombstoned.With(labels).Set(BoolToFloat64(validator.Tombstoned))
+		m.validatorActive.With(labels).Set(BoolToFloat64(validator.Active))
+
+		if params.SignedBlocksWindow > 0 {
+			m.validatorMissedBlocksRatio.With(labels).Set(
+				float64(validator.MissedBlocks) / float64(params.SignedBlocksWindow),
+			)
+		}
+
+		if params.AvgBlockTimeKnown() && !validator.Jailed {
+			entry := ReportEntry{MissingBlocks: validator.MissedBlocks}
+			m.validatorTimeToJail.With(labels).Set(entry.GetTimeToJail(&params).Seconds())
+		} else {
+			m.validatorTimeToJail.Delete(labels)
+		}
+	}
+
+	// Removing validators that are not monitored anymore, or whose moniker has changed.
+	for address, labels := range m.validatorLabels[chain] {
+		if newLabels[address] != nil && newLabels[address]["moniker"] == labels["moniker"] {
+			continue
+		}
+
+		m.validatorMissedBlocks.Delete(labels)
+		m.validatorMissedBlocksRatio.Delete(labels)
+		m.validatorJailed.Delete(labels)
+		m.validatorTombstoned.Delete(labels)
+		m.validatorActive.Delete(labels)
+		m.validatorTimeToJail.Delete(labels)
+	}
+
+	m.validatorLabels[chain] = newLabels
+}
+
+func (m *Metrics) SetParams(chain string, params Params) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.signedBlocksWindow.WithLabelValues(chain).Set(float64(params.SignedBlocksWindow))
+	m.missedBlocksToJail.WithLabelValues(chain).Set(float64(params.MissedBlocksToJail))
+	m.downtimeJailDuration.WithLabelValues(chain).Set(params.DowntimeJailDuration.Seconds())
+
+	if params.AvgBlockTimeKnown() {
+		m.avgBlockTime.WithLabelValues(chain).Set(params.AvgBlockTime)
+	}
+}
+
+func (m *Metrics) ObservePoll(chain string, duration time.Duration) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.pollDuration.WithLabelValues(chain).Observe(duration.Seconds())
+}
+
+func (m *Metrics) IncPollErrors(chain string) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.pollErrors.WithLabelValues(chain).Inc()
+}
+
+func (m *Metrics) IncGrpcErrors(chain string) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.grpcErrors.WithLabelValues(chain).Inc()
+}
+
+func (m *Metrics) IncReporterFailures(chain, reporter string) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.reporterFailures.WithLabelValues(chain, reporter).Inc()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	FailoverPolicyPriority   = "priority"
+	FailoverPolicyRoundRobin = "round-robin"
+)
+
+// NodesPool keeps track of which of the configured nodes are healthy and decides
+// which one to send requests to. With the priority policy, the first healthy node
+// is used, so once a node with higher priority becomes healthy again, requests go back to it.
+// With the round-robin policy, requests are spread across all the healthy nodes.
+type NodesPool struct {
+	Addresses []string
+	Policy    string
+	Checker   func(address string) error
+	Logger    zerolog.Logger
+
+	mutex   sync.Mutex
+	healthy []bool
+	next    int
+}
+
+func NewNodesPool(
+	addresses []string,
+	policy string,
+	checker func(address string) error,
+	logger *zerolog.Logger,
+) *NodesPool {
+	healthy := make([]bool, len(addresses))
+	for index := range healthy {
+		healthy[index] = true
+	}
+
+	return &NodesPool{
+		Addresses: addresses,
+		Policy:    policy,
+		Checker:   checker,
+		Logger:    logger.With().Str("component", "nodes_pool").Logger(),
+		healthy:   healthy,
+	}
+}
+
+// GetNodes returns all the nodes in the order they should be tried in:
+// healthy ones first, ordered according to the policy, then the unhealthy ones,
+// in case they are back but the health check has not noticed it yet.
+func (p *NodesPool) GetNodes() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	offset := 0
+	if p.Policy == FailoverPolicyRoundRobin {
+		offset = p.next
+		p.next = (p.next + 1) % len(p.Addresses)
+	}
+
+	healthy := []string{}
+	unhealthy := []string{}
+
+	for i := 0; i < len(p.Addresses); i++ {
+		index := (offset + i) % len(p.Addresses)
+		if p.healthy[index] {
+			healthy = append(healthy, p.Addresses[index])
+		} else {
+			unhealthy = append(unhealthy, p.Addresses[index])
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// GetNode returns the node that should be used for the next request.
+func (p *NodesPool) GetNode() string {
+	return p.GetNodes()[0]
+}
+
+func (p *NodesPool) SetHealthy(address string, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for index, nodeAddress := range p.Addresses {
+		if nodeAddress != address {
+			continue
+		}
+
+		healthy := err == nil
+		if p.healthy[index] == healthy {
+			return
+		}
+
+		p.healthy[index] = healthy
+
+		if healthy {
+			p.Logger.Info().Str("address", address).Msg("Node is healthy again")
+		} else {
+			p.Logger.Warn().Err(err).Str("address", address).Msg("Node is unhealthy, failing over")
+		}
+
+		return
+	}
+}
+
+// StartHealthChecks checks all the nodes every interval. Never returns.
+func (p *NodesPool) StartHealthChecks(interval time.Duration) {
+	for {
+		for _, address := range p.Addresses {
+			p.SetHealthy(address, p.Checker(address))
+		}
+
+		time.Sleep(interval)
+	}
+}
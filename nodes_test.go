@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNodesPoolGetNodes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		policy    string
+		unhealthy []string
+		recovered []string
+		// Nodes returned by each of the consecutive calls.
+		expected [][]string
+	}{
+		{
+			name:   "priority uses the first node",
+			policy: FailoverPolicyPriority,
+			expected: [][]string{
+				{"a", "b", "c"},
+				{"a", "b", "c"},
+			},
+		},
+		{
+			name:      "priority fails over to the next healthy node",
+			policy:    FailoverPolicyPriority,
+			unhealthy: []string{"a"},
+			expected: [][]string{
+				{"b", "c", "a"},
+				{"b", "c", "a"},
+			},
+		},
+		{
+			name:      "priority goes back to the node once it's healthy",
+			policy:    FailoverPolicyPriority,
+			unhealthy: []string{"a", "b"},
+			recovered: []string{"a"},
+			expected: [][]string{
+				{"a", "c", "b"},
+			},
+		},
+		{
+			name:   "round-robin rotates the nodes",
+			policy: FailoverPolicyRoundRobin,
+			expected: [][]string{
+				{"a", "b", "c"},
+				{"b", "c", "a"},
+				{"c", "a", "b"},
+				{"a", "b", "c"},
+			},
+		},
+		{
+			name:      "round-robin puts unhealthy nodes last",
+			policy:    FailoverPolicyRoundRobin,
+			unhealthy: []string{"b"},
+			expected: [][]string{
+				{"a", "c", "b"},
+				{"c", "a", "b"},
+				{"c", "a", "b"},
+				{"a", "c", "b"},
+			},
+		},
+		{
+			name:      "all nodes unhealthy are still tried",
+			policy:    FailoverPolicyPriority,
+			unhealthy: []string{"a", "b", "c"},
+			expected: [][]string{
+				{"a", "b", "c"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			pool := NewNodesPool([]string{"a", "b", "c"}, testCase.policy, nil, &logger)
+
+			for _, address := range testCase.unhealthy {
+				pool.SetHealthy(address, errors.New("node is down"))
+			}
+
+			for _, address := range testCase.recovered {
+				pool.SetHealthy(address, nil)
+			}
+
+			for index, expected := range testCase.expected {
+				if nodes := pool.GetNodes(); !reflect.DeepEqual(nodes, expected) {
+					t.Errorf("call %d: expected %v, got %v", index, expected, nodes)
+				}
+			}
+		})
+	}
+}
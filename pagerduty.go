@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	PagerDutyEventTrigger = "trigger"
+	PagerDutyEventResolve = "resolve"
+
+	PagerDutySource      = "missed-blocks-checker"
+	PagerDutyMaxAttempts = 3
+	PagerDutyTimeout     = 10 * time.Second
+)
+
+// PagerDutyReporter sends PagerDuty Events API v2 events, triggering an incident when
+// a validator enters a missed blocks group or has an event with a severity configured,
+// and resolving it once the validator is back to the first group, is unjailed or is online again.
+// Events use a dedup key per chain and validator, so they all update the same incident.
+type PagerDutyReporter struct {
+	PagerDutyConfig PagerDutyConfig
+	Logger          zerolog.Logger
+
+	Client *http.Client
+}
+
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+	Links       []PagerDutyLink   `json:"links,omitempty"`
+}
+
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component"`
+	Class         string            `json:"class"`
+	CustomDetails map[string]string `json:"custom_details"`
+}
+
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+func NewPagerDutyReporter(
+	pagerDutyConfig PagerDutyConfig,
+	logger *zerolog.Logger,
+) *PagerDutyReporter {
+	return &PagerDutyReporter{
+		PagerDutyConfig: pagerDutyConfig,
+		Logger:          logger.With().Str("component", "pagerduty_reporter").Logger(),
+	}
+}
+
+func GetPagerDutyDedupKey(chain, address string) string {
+	return fmt.Sprintf("%s/%s/%s", PagerDutySource, chain, address)
+}
+
+// IsPagerDutyTriggerDirection checks whether the direction means the validator got worse,
+// so it can trigger an incident with a severity from DirectionSeverities.
+// Entering a missed blocks group uses GroupSeverities instead.
+func IsPagerDutyTriggerDirection(direction Direction) bool {
+	switch direction {
+	case JAILED, TOMBSTONED, OFFLINE, JAIL_WARNING:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetEventAction returns whether the entry should trigger or resolve an incident,
+// along with the severity if triggering, and false if it should be ignored.
+// Only validators getting worse trigger an incident, so the one resolved by a human
+// is not triggered again while the validator is recovering.
+func (r PagerDutyReporter) GetEventAction(entry ReportEntry) (string, string, bool) {
+	switch {
+	case entry.Direction == INCREASING:
+		severity, ok := r.PagerDutyConfig.GetGroupSeverity(entry.Group)
+		return PagerDutyEventTrigger, severity, ok
+	case entry.Direction == DECREASING:
+		return PagerDutyEventResolve, "", entry.Group == 0
+	case entry.Direction == UNJAILED, entry.Direction == ONLINE:
+		return PagerDutyEventResolve, "", true
+	case IsPagerDutyTriggerDirection(entry.Direction):
+		severity, ok := r.PagerDutyConfig.GetDirectionSeverity(entry.Direction)
+		return PagerDutyEventTrigger, severity, ok
+	default:
+		return "", "", false
+	}
+}
+
+func (r PagerDutyReporter) GetEvents(report Report) []PagerDutyEvent {
+	chain := report.GetChainName()
+	events := []PagerDutyEvent{}
+
+	for _, entry := range report.Entries {
+		action, severity, ok := r.GetEventAction(entry)
+		if !ok {
+			continue
+		}
+
+		event := PagerDutyEvent{
+			RoutingKey:  r.PagerDutyConfig.RoutingKey,
+			EventAction: action,
+			DedupKey:    GetPagerDutyDedupKey(chain, entry.ValidatorAddress),
+		}
+
+		if action == PagerDutyEventTrigger {
+			details := map[string]string{
+				"chain":         chain,
+				"validator":     entry.ValidatorAddress,
+				"moniker":       entry.ValidatorMoniker,
+				"direction":     entry.Direction.String(),
+				"missed_blocks": fmt.Sprintf("%d/%d", entry.MissingBlocks, report.Params.SignedBlocksWindow),
+			}
+
+			if (entry.Direction == INCREASING || entry.Direction == JAIL_WARNING) && report.Params.AvgBlockTimeKnown() {
+				details["time_to_jail"] = entry.GetTimeToJailDesc(&report.Params)
+			}
+
+			event.Payload = &PagerDutyPayload{
+				Summary:       fmt.Sprintf("%s: %s %s", chain, entry.ValidatorMoniker, entry.Description),
+				Source:        PagerDutySource,
+				Severity:      severity,
+				Component:     chain,
+				Class:         entry.Direction.String(),
+				CustomDetails: details,
+			}
+			event.Links = []PagerDutyLink{{
+				Href: report.ChainInfoConfig.GetValidatorURL(entry.ValidatorAddress),
+				Text: entry.ValidatorMoniker,
+			}}
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (r PagerDutyReporter) Serialize(report Report) string {
+	events, err := json.Marshal(r.GetEvents(report))
+	if err != nil {
+		r.Logger.Error().Err(err).Msg("Could not serialize report")
+		return ""
+	}
+
+	return string(events)
+}
+
+func (r *PagerDutyReporter) Init() {
+	if r.PagerDutyConfig.RoutingKey == "" {
+		r.Logger.Debug().Msg("PagerDuty routing key not set, not creating PagerDuty reporter.")
+		return
+	}
+
+	r.Client = &http.Client{Timeout: PagerDutyTimeout}
+}
+
+func (r PagerDutyReporter) Enabled() bool {
+	return r.PagerDutyConfig.RoutingKey != ""
+}
+
+// SendReport sends all the events, retrying each one separately, so a failed one
+// doesn't prevent the next ones, like resolves, from being sent.
+func (r PagerDutyReporter) SendReport(report Report) error {
+	failedEvents := []string{}
+
+	for _, event := range r.GetEvents(report) {
+		event := event
+		err := RetryWithBackoffAttempts(&r.Logger, "send PagerDuty event", PagerDutyMaxAttempts, func() error {
+			return r.send(event)
+		})
+		if err != nil {
+			failedEvents = append(failedEvents, fmt.Sprintf("%s %s", event.EventAction, event.DedupKey))
+		}
+	}
+
+	if len(failedEvents) > 0 {
+		return fmt.Errorf("could not send PagerDuty events: %s", strings.Join(failedEvents, ", "))
+	}
+
+	return nil
+}
+
+func (r PagerDutyReporter) send(event PagerDutyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.Client.Post(r.PagerDutyConfig.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return NewHTTPStatusError(response.StatusCode, responseBody)
+	}
+
+	return nil
+}
+
+func (r PagerDutyReporter) Name() string {
+	return "PagerDutyReporter"
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPagerDutyGetEventAction(t *testing.T) {
+	defaultConfig := PagerDutyConfig{}
+	defaultConfig.Validate()
+
+	customConfig := PagerDutyConfig{
+		GroupSeverities:     map[string]string{"2": "warning", "3": "critical"},
+		DirectionSeverities: map[string]string{"offline": "error", "jail_warning": "warning"},
+	}
+	customConfig.Validate()
+
+	testCases := []struct {
+		name             string
+		config           PagerDutyConfig
+		entry            ReportEntry
+		expectedAction   string
+		expectedSeverity string
+		expectedOk       bool
+	}{
+		{
+			name:             "entering a group triggers",
+			config:           defaultConfig,
+			entry:            ReportEntry{Direction: INCREASING, Group: 1},
+			expectedAction:   PagerDutyEventTrigger,
+			expectedSeverity: "error",
+			expectedOk:       true,
+		},
+		{
+			name:             "entering the first group is ignored",
+			config:           defaultConfig,
+			entry:            ReportEntry{Direction: INCREASING, Group: 0},
+			expectedAction:   PagerDutyEventTrigger,
+			expectedSeverity: "error",
+			expectedOk:       false,
+		},
+		{
+			name:             "entering a group uses its severity",
+			config:           customConfig,
+			entry:            ReportEntry{Direction: INCREASING, Group: 3},
+			expectedAction:   PagerDutyEventTrigger,
+			expectedSeverity: "critical",
+			expectedOk:       true,
+		},
+		{
+			name:           "entering a group without a severity is ignored",
+			config:         customConfig,
+			entry:          ReportEntry{Direction: INCREASING, Group: 1},
+			expectedAction: PagerDutyEventTrigger,
+			expectedOk:     false,
+		},
+		{
+			name:           "going back to the first group resolves",
+			config:         defaultConfig,
+			entry:          ReportEntry{Direction: DECREASING, Group: 0},
+			expectedAction: PagerDutyEventResolve,
+			expectedOk:     true,
+		},
+		{
+			name:           "going back to a higher group is ignored",
+			config:         defaultConfig,
+			entry:          ReportEntry{Direction: DECREASING, Group: 1},
+			expectedAction: PagerDutyEventResolve,
+			expectedOk:     false,
+		},
+		{
+			name:           "unjailed resolves",
+			config:         defaultConfig,
+			entry:          ReportEntry{Direction: UNJAILED},
+			expectedAction: PagerDutyEventResolve,
+			expectedOk:     true,
+		},
+		{
+			name:           "online resolves",
+			config:         defaultConfig,
+			entry:          ReportEntry{Direction: ONLINE},
+			expectedAction: PagerDutyEventResolve,
+			expectedOk:     true,
+		},
+		{
+			name:             "jailed triggers with the default severity",
+			config:           defaultConfig,
+			entry:            ReportEntry{Direction: JAILED},
+			expectedAction:   PagerDutyEventTrigger,
+			expectedSeverity: "critical",
+			expectedOk:       true,
+		},
+		{
+			name:           "offline without a severity is ignored",
+			config:         defaultConfig,
+			entry:          ReportEntry{Direction: OFFLINE},
+			expectedAction: PagerDutyEventTrigger,
+			expectedOk:     false,
+		},
+		{
+			name:             "jail warning triggers with its severity",
+			config:           customConfig,
+			entry:            ReportEntry{Direction: JAIL_WARNING},
+			expectedAction:   PagerDutyEventTrigger,
+			expectedSeverity: "warning",
+			expectedOk:       true,
+		},
+		{
+			name:       "activated is ignored",
+			config:     defaultConfig,
+			entry:      ReportEntry{Direction: ACTIVATED},
+			expectedOk: false,
+		},
+		{
+			name:       "unjail ready is ignored",
+			config:     defaultConfig,
+			entry:      ReportEntry{Direction: UNJAIL_READY},
+			expectedOk: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reporter := PagerDutyReporter{PagerDutyConfig: testCase.config}
+
+			action, severity, ok := reporter.GetEventAction(testCase.entry)
+			if ok != testCase.expectedOk {
+				t.Fatalf("expected ok to be %t, got %t", testCase.expectedOk, ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if action != testCase.expectedAction || severity != testCase.expectedSeverity {
+				t.Errorf(
+					"expected %q with severity %q, got %q with severity %q",
+					testCase.expectedAction,
+					testCase.expectedSeverity,
+					action,
+					severity,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Average block time changes smaller than this are not announced, as it drifts constantly.
+const AvgBlockTimeChangeThreshold = 0.1
+
+// ParamsRefresher recalculates the chain params in the background, as both slashing params
+// and block time can change over time, via governance or after upgrades.
+type ParamsRefresher struct {
+	GRPC               *TendermintGRPC
+	BlockTimeEstimator *BlockTimeEstimator
+	Interval           time.Duration
+	Logger             zerolog.Logger
+
+	Updates chan Params
+}
+
+func NewParamsRefresher(
+	grpc *TendermintGRPC,
+	blockTimeEstimator *BlockTimeEstimator,
+	interval time.Duration,
+	logger *zerolog.Logger,
+) *ParamsRefresher {
+	return &ParamsRefresher{
+		GRPC:               grpc,
+		BlockTimeEstimator: blockTimeEstimator,
+		Interval:           interval,
+		Logger:             logger.With().Str("component", "params_refresher").Logger(),
+		Updates:            make(chan Params, 1),
+	}
+}
+
+func (r *ParamsRefresher) GetParams() (Params, error) {
+	slashingParams, err := r.GRPC.GetSlashingParams()
+	if err != nil {
+		return Params{}, err
+	}
+
+	avgBlockTime, samples := r.BlockTimeEstimator.Estimate()
+
+	return Params{
+		AvgBlockTime:        avgBlockTime,
+		AvgBlockTimeSamples: samples,
+		SignedBlocksWindow:  slashingParams.SignedBlocksWindow,
+		MissedBlocksToJail:  slashingParams.MissedBlocksToJail,
+
+		DowntimeJailDuration: slashingParams.DowntimeJailDuration,
+	}, nil
+}
+
+// Start sends the fresh params to Updates every Interval. Never returns.
+func (r *ParamsRefresher) Start() {
+	for {
+		time.Sleep(r.Interval)
+
+		params, err := r.GetParams()
+		if err != nil {
+			r.Logger.Error().Err(err).Msg("Could not refresh chain params")
+			continue
+		}
+
+		r.Logger.Debug().
+			Int64("signedBlocksWindow", params.SignedBlocksWindow).
+			Int64("missedBlocksToJail", params.MissedBlocksToJail).
+			Float64("avgBlockTime", params.AvgBlockTime).
+			Msg("Chain params refreshed")
+
+		// Dropping the previous update if it was not applied yet, the fresh one is better anyway.
+		select {
+		case <-r.Updates:
+		default:
+		}
+
+		r.Updates <- params
+	}
+}
+
+// GetParamsChanges returns the changes between the params worth announcing.
+func GetParamsChanges(oldParams, newParams Params) []ParamChange {
+	changes := []ParamChange{}
+
+	if oldParams.SignedBlocksWindow != newParams.SignedBlocksWindow {
+		changes = append(changes, ParamChange{
+			Name: "Signed blocks window",
+			Old:  fmt.Sprintf("%d", oldParams.SignedBlocksWindow),
+			New:  fmt.Sprintf("%d", newParams.SignedBlocksWindow),
+		})
+	}
+
+	if oldParams.MissedBlocksToJail != newParams.MissedBlocksToJail {
+		changes = append(changes, ParamChange{
+			Name: "Missed blocks to jail",
+			Old:  fmt.Sprintf("%d", oldParams.MissedBlocksToJail),
+			New:  fmt.Sprintf("%d", newParams.MissedBlocksToJail),
+		})
+	}
+
+	if oldParams.DowntimeJailDuration != newParams.DowntimeJailDuration {
+		changes = append(changes, ParamChange{
+			Name: "Downtime jail duration",
+			Old:  FormatDuration(oldParams.DowntimeJailDuration),
+			New:  FormatDuration(newParams.DowntimeJailDuration),
+		})
+	}
+
+	if oldParams.AvgBlockTimeKnown() &&
+		math.Abs(newParams.AvgBlockTime-oldParams.AvgBlockTime)/oldParams.AvgBlockTime > AvgBlockTimeChangeThreshold {
+		changes = append(changes, ParamChange{
+			Name: "Average block time",
+			Old:  fmt.Sprintf("%.2fs", oldParams.AvgBlockTime),
+			New:  fmt.Sprintf("%.2fs", newParams.AvgBlockTime),
+		})
+	}
+
+	return changes
+}
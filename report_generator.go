@@ -2,48 +2,289 @@ package main
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
 	"github.com/rs/zerolog"
 )
 
 type ReportGenerator struct {
-	Params   Params
-	Config   *AppConfig
-	gRPC     *TendermintGRPC
-	Logger   zerolog.Logger
-	State    ValidatorsState
-	Registry codectypes.InterfaceRegistry
+	Params     *Params
+	Config     *ChainConfig
+	gRPC       *TendermintGRPC
+	RPC        *TendermintRPC
+	StateStore *StateStore
+	Database   *Database
+	Metrics    *Metrics
+	Logger     zerolog.Logger
+	Registry   codectypes.InterfaceRegistry
+
+	State       ValidatorsState
+	StateHeight int64
+	StateTime   time.Time
+	// Set if State was loaded from StateStore and not yet compared with the fresh one,
+	// so the changes found are the ones that happened while the checker was offline.
+	StateRestored bool
+	// Last block which signatures were processed, if tracking block signatures.
+	LastBlockHeight int64
+	// Amount of blocks each validator missed in a row, keyed by consensus address.
+	MissedBlocksStreaks map[string]int64
+	// Amount of polls in a row the validators state could not be fetched.
+	FailedPolls int64
+	// Set if the monitoring degraded alert was sent and the recovery one was not yet.
+	MonitoringDegraded bool
+	// Amount of jail warning thresholds already fired for each validator approaching jail,
+	// keyed by consensus address.
+	JailWarnings map[string]int
+	// When the validators that can be unjailed were last notified about it, keyed by operator address.
+	UnjailReminders map[string]time.Time
+	// Validators which state could not be fetched during the last successful poll, keyed by operator address.
+	FailedValidators map[string]FailedValidator
+
+	// Held while generating a report, so the state is not saved by Stop in the middle of a poll.
+	mutex sync.Mutex
 }
 
 func NewReportGenerator(
-	params Params,
+	params *Params,
 	grpc *TendermintGRPC,
-	config *AppConfig,
+	rpc *TendermintRPC,
+	stateStore *StateStore,
+	database *Database,
+	metrics *Metrics,
+	config *ChainConfig,
 	logger *zerolog.Logger,
 	registry codectypes.InterfaceRegistry,
 ) *ReportGenerator {
 	return &ReportGenerator{
-		Params:   params,
-		gRPC:     grpc,
-		Config:   config,
-		Logger:   logger.With().Str("component", "report_generator").Logger(),
-		Registry: registry,
+		Params:     params,
+		gRPC:       grpc,
+		RPC:        rpc,
+		StateStore: stateStore,
+		Database:   database,
+		Metrics:    metrics,
+		Config:     config,
+		Logger:     logger.With().Str("component", "report_generator").Logger(),
+		Registry:   registry,
+
+		MissedBlocksStreaks: make(map[string]int64),
+		JailWarnings:        make(map[string]int),
+		UnjailReminders:     make(map[string]time.Time),
+		FailedValidators:    make(map[string]FailedValidator),
+	}
+}
+
+func (g *ReportGenerator) LoadState() {
+	snapshot, err := g.StateStore.Load()
+	if err != nil {
+		g.Logger.Error().Err(err).Msg("Could not load previous state, starting from scratch")
+		return
+	}
+
+	if snapshot == nil {
+		return
+	}
+
+	g.State = snapshot.State
+	g.StateHeight = snapshot.Height
+	g.StateTime = snapshot.Time
+	g.StateRestored = true
+	g.LastBlockHeight = snapshot.LastBlockHeight
+
+	if snapshot.MissedBlocksStreaks != nil {
+		g.MissedBlocksStreaks = snapshot.MissedBlocksStreaks
+	}
+
+	g.Logger.Info().
+		Int64("height", snapshot.Height).
+		Time("time", snapshot.Time).
+		Int("validators", len(snapshot.State)).
+		Msg("Loaded previous state")
+}
+
+// SaveState stores the state fetched at the given height, 0 if the height is not known.
+func (g *ReportGenerator) SaveState(state ValidatorsState, height int64) {
+	g.State = state
+	g.StateHeight = height
+	g.StateTime = time.Now()
+	g.StateRestored = false
+
+	g.persistState()
+}
+
+// Stop waits for the poll in progress to finish, saves the state once more
+// and prevents the next polls, so the state store and the database can be closed.
+func (g *ReportGenerator) Stop() {
+	g.mutex.Lock()
+
+	if len(g.State) == 0 {
+		return
+	}
+
+	g.persistState()
+}
+
+func (g *ReportGenerator) persistState() {
+	if !g.StateStore.Enabled() && !g.Database.Enabled() {
+		return
+	}
+
+	snapshot := StateSnapshot{
+		Height: g.StateHeight,
+		Time:   g.StateTime,
+		State:  g.State,
+
+		LastBlockHeight:     g.LastBlockHeight,
+		MissedBlocksStreaks: g.MissedBlocksStreaks,
+	}
+
+	if err := g.StateStore.Save(snapshot); err != nil {
+		g.Logger.Error().Err(err).Msg("Could not save state")
+	}
+
+	if err := g.Database.SaveSnapshot(g.Config.Name, snapshot); err != nil {
+		g.Logger.Error().Err(err).Msg("Could not save state to database")
 	}
 }
 
-func (g *ReportGenerator) GetNewState() (ValidatorsState, error) {
+func (g *ReportGenerator) GetNewState() (ValidatorsState, []FailedValidator, error) {
 	g.Logger.Debug().Msg("Querying for signing infos...")
 
-	state, err := g.gRPC.GetValidatorsState()
+	state, failed, err := g.gRPC.GetValidatorsState()
 	if err != nil {
 		g.Logger.Error().Err(err).Msg("Could not query for signing infos")
-		return nil, err
+		return nil, nil, err
+	}
+
+	monitoredFailed := []FailedValidator{}
+	for _, validator := range failed {
+		if g.Config.IsValidatorMonitored(validator.Address) {
+			monitoredFailed = append(monitoredFailed, validator)
+		}
 	}
 
 	return FilterMap(state, func(v ValidatorState) bool {
 		return g.Config.IsValidatorMonitored(v.Address)
-	}), nil
+	}), monitoredFailed, nil
+}
+
+func (g *ReportGenerator) GetNewBlocksSignatures() []BlockSignatures {
+	if !g.Config.TrackBlockSignatures {
+		return []BlockSignatures{}
+	}
+
+	latestHeight, err := g.RPC.GetLatestHeight()
+	if err != nil {
+		g.Logger.Error().Err(err).Msg("Could not get latest block height")
+		return []BlockSignatures{}
+	}
+
+	// The latest block's commit is not canonical yet and might lack some signatures,
+	// so processing blocks till the previous one.
+	to := latestHeight - 1
+
+	if g.LastBlockHeight == 0 {
+		g.Logger.Info().Int64("height", to).Msg("Starting tracking block signatures")
+		g.LastBlockHeight = to
+		return []BlockSignatures{}
+	}
+
+	from := g.LastBlockHeight + 1
+	if to-from+1 > g.Config.MaxBlocksPerPoll {
+		g.Logger.Warn().
+			Int64("from", from).
+			Int64("to", to).
+			Int64("max", g.Config.MaxBlocksPerPoll).
+			Msg("Too many blocks to process, skipping some of them")
+		from = to - g.Config.MaxBlocksPerPoll + 1
+
+		// The streaks cannot be continued over the skipped blocks.
+		g.MissedBlocksStreaks = make(map[string]int64)
+	}
+
+	if from > to {
+		return []BlockSignatures{}
+	}
+
+	g.Logger.Debug().Int64("from", from).Int64("to", to).Msg("Querying for blocks signatures...")
+
+	blocks, err := g.RPC.GetBlocksSignatures(from, to)
+	if err != nil {
+		g.Logger.Error().
+			Err(err).
+			Int("fetched", len(blocks)).
+			Msg("Could not query for blocks signatures")
+	}
+
+	if len(blocks) > 0 {
+		g.LastBlockHeight = blocks[len(blocks)-1].Height
+	}
+
+	return blocks
+}
+
+// GetMissedBlocksStreaksReportEntries walks through the new blocks and returns an entry
+// once a validator missed the configured amount of blocks in a row, and another one
+// once it signs a block again after that.
+func (g *ReportGenerator) GetMissedBlocksStreaksReportEntries(
+	blocks []BlockSignatures,
+	state ValidatorsState,
+) []ReportEntry {
+	entries := []ReportEntry{}
+
+	for _, block := range blocks {
+		for address, validator := range state {
+			threshold := g.Config.MissedBlocksStreakConfig.GetThreshold(validator.Address)
+			if threshold <= 0 || !validator.Active {
+				continue
+			}
+
+			streak := g.MissedBlocksStreaks[address]
+
+			if !block.Signers[address] {
+				g.MissedBlocksStreaks[address] = streak + 1
+
+				if streak+1 == threshold {
+					g.Logger.Debug().
+						Str("address", validator.Address).
+						Int64("height", block.Height).
+						Int64("streak", streak+1).
+						Msg("Validator has missed too many blocks in a row")
+					entries = append(entries, ReportEntry{
+						ValidatorAddress: validator.Address,
+						ValidatorMoniker: validator.Moniker,
+						Emoji:            OfflineEmoji,
+						Description:      fmt.Sprintf(OfflineDesc, streak+1),
+						MissingBlocks:    validator.MissedBlocks,
+						Direction:        OFFLINE,
+					})
+				}
+
+				continue
+			}
+
+			delete(g.MissedBlocksStreaks, address)
+
+			if streak >= threshold {
+				g.Logger.Debug().
+					Str("address", validator.Address).
+					Int64("height", block.Height).
+					Int64("streak", streak).
+					Msg("Validator is signing blocks again")
+				entries = append(entries, ReportEntry{
+					ValidatorAddress: validator.Address,
+					ValidatorMoniker: validator.Moniker,
+					Emoji:            OnlineEmoji,
+					Description:      fmt.Sprintf(OnlineDesc, streak),
+					MissingBlocks:    validator.MissedBlocks,
+					Direction:        ONLINE,
+				})
+			}
+		}
+	}
+
+	return entries
 }
 
 func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorState) (*ReportEntry, bool) {
@@ -70,12 +311,14 @@ func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorSt
 	if newState.Jailed && !oldState.Jailed {
 		g.Logger.Debug().
 			Str("address", oldState.Address).
+			Time("jailedUntil", newState.JailedUntil).
 			Msg("Validator is jailed")
+		delete(g.UnjailReminders, newState.Address)
 		return &ReportEntry{
 			ValidatorAddress: newState.Address,
 			ValidatorMoniker: newState.Moniker,
 			Emoji:            JailedEmoju,
-			Description:      JailedDesc,
+			Description:      newState.GetJailedDesc(g.Params.DowntimeJailDuration),
 			Direction:        JAILED,
 		}, true
 	}
@@ -85,6 +328,7 @@ func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorSt
 		g.Logger.Debug().
 			Str("address", oldState.Address).
 			Msg("Validator is unjailed")
+		delete(g.UnjailReminders, newState.Address)
 		return &ReportEntry{
 			ValidatorAddress: newState.Address,
 			ValidatorMoniker: newState.Moniker,
@@ -94,12 +338,9 @@ func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorSt
 		}, true
 	}
 
-	// 4. If validator is and was jailed - do nothing.
+	// 4. If validator is and was jailed - only notify if it can be unjailed already.
 	if newState.Jailed && oldState.Jailed {
-		g.Logger.Debug().
-			Str("address", oldState.Address).
-			Msg("Validator is and was jailed - no need to send report")
-		return nil, false
+		return g.GetUnjailReadyReportEntry(newState)
 	}
 
 	// 5. Validator isn't and wasn't jailed.
@@ -111,11 +352,12 @@ func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorSt
 		g.Logger.Error().Err(oldGroupErr).Msg("Could not get old group")
 		return nil, false
 	}
-	newGroup, newGroupErr := g.Config.MissedBlocksGroups.GetGroup(newState.MissedBlocks)
+	newGroupIndex, newGroupErr := g.Config.MissedBlocksGroups.GetGroupIndex(newState.MissedBlocks)
 	if newGroupErr != nil {
 		g.Logger.Error().Err(newGroupErr).Msg("Could not get new group")
 		return nil, false
 	}
+	newGroup := g.Config.MissedBlocksGroups[newGroupIndex]
 
 	if oldGroup.Start == newGroup.Start {
 		g.Logger.Debug().
@@ -137,6 +379,7 @@ func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorSt
 		ValidatorAddress: newState.Address,
 		ValidatorMoniker: newState.Moniker,
 		MissingBlocks:    newState.MissedBlocks,
+		Group:            newGroupIndex,
 	}
 
 	if oldState.MissedBlocks < newState.MissedBlocks {
@@ -164,28 +407,125 @@ func (g *ReportGenerator) GetValidatorReportEntry(oldState, newState ValidatorSt
 	return entry, true
 }
 
+// GetValidatorActiveSetReportEntry checks whether a validator entered or left the active set
+// for reasons other than jailing, which is reported separately.
+func (g *ReportGenerator) GetValidatorActiveSetReportEntry(oldState, newState ValidatorState) (*ReportEntry, bool) {
+	if oldState.Active == newState.Active || oldState.Jailed || newState.Jailed {
+		return nil, false
+	}
+
+	entry := &ReportEntry{
+		ValidatorAddress: newState.Address,
+		ValidatorMoniker: newState.Moniker,
+		MissingBlocks:    newState.MissedBlocks,
+	}
+
+	if newState.Active {
+		g.Logger.Debug().
+			Str("address", newState.Address).
+			Msg("Validator entered the active set")
+		entry.Direction = ACTIVATED
+		entry.Emoji = ActivatedEmoji
+		entry.Description = ActivatedDesc
+	} else {
+		g.Logger.Debug().
+			Str("address", newState.Address).
+			Msg("Validator dropped out of the active set")
+		entry.Direction = DEACTIVATED
+		entry.Emoji = DeactivatedEmoji
+		entry.Description = DeactivatedDesc
+	}
+
+	return entry, true
+}
+
 func (g *ReportGenerator) GenerateReport() *Report {
-	newState, err := g.GetNewState()
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	newState, failed, err := g.GetNewState()
 	if err != nil {
 		g.Logger.Error().Err(err).Msg("Error getting new state")
-		return &Report{}
+		g.Metrics.IncPollErrors(g.Config.GetName())
+		return g.NewReport(g.GetFailedPollAlerts(err), nil, nil, nil)
+	}
+
+	alerts := g.GetSuccessfulPollAlerts()
+
+	height, err := g.RPC.GetLatestHeight()
+	if err != nil {
+		g.Logger.Warn().Err(err).Msg("Could not get latest block height")
+	}
+
+	// Keeping the previous state for validators that could not be fetched,
+	// so they are not reported as removed and are compared next time.
+	for _, validator := range failed {
+		g.Logger.Warn().
+			Str("address", validator.Address).
+			Str("error", validator.Error).
+			Msg("Could not fetch validator state, keeping the previous one")
+
+		if oldState, ok := g.State[validator.ConsensusAddress]; ok {
+			newState[validator.ConsensusAddress] = oldState
+		}
+	}
+
+	newlyFailed, recovered := g.GetFailedValidatorsChanges(failed, newState)
+
+	g.Metrics.SetValidatorsState(g.Config.GetName(), newState, *g.Params)
+
+	blocks := g.GetNewBlocksSignatures()
+	missedHeights := GetMissedHeights(blocks, newState)
+
+	for address, heights := range missedHeights {
+		g.Logger.Debug().
+			Str("address", address).
+			Ints64("heights", heights).
+			Msg("Validator missed blocks")
 	}
 
 	if len(g.State) == 0 {
 		g.Logger.Info().Msg("No previous state, skipping.")
-		g.State = newState
-		return &Report{}
+		g.SaveState(newState, height)
+		return g.NewReport(alerts, nil, newlyFailed, recovered)
 	}
 
-	entries := []ReportEntry{}
+	if g.StateRestored {
+		g.Logger.Info().
+			Int64("height", g.StateHeight).
+			Time("time", g.StateTime).
+			Msg("Comparing with the state saved before restart")
+	}
+
+	entries := g.GetMissedBlocksStreaksReportEntries(blocks, newState)
 
 	for address, info := range newState {
 		oldState, ok := g.State[address]
 		if !ok {
-			g.Logger.Warn().Str("address", address).Msg("No old state present for address")
+			g.Logger.Info().Str("address", address).Msg("No old state present for address, validator is new")
+			entries = append(entries, ReportEntry{
+				ValidatorAddress: info.Address,
+				ValidatorMoniker: info.Moniker,
+				Emoji:            CreatedEmoji,
+				Description:      CreatedDesc,
+				Direction:        CREATED,
+				WhileOffline:     g.StateRestored,
+			})
 			continue
 		}
 
+		if entry, present := g.GetValidatorActiveSetReportEntry(oldState, info); present {
+			entry.WhileOffline = g.StateRestored
+			entries = append(entries, *entry)
+		}
+
+		missRate, missRateKnown := g.GetMissRate(oldState, info, blocks, missedHeights[address], height)
+
+		if entry, present := g.GetJailWarningReportEntry(address, oldState, info, missRate, missRateKnown); present {
+			entry.WhileOffline = g.StateRestored
+			entries = append(entries, *entry)
+		}
+
 		entry, present := g.GetValidatorReportEntry(oldState, info)
 		if !present {
 			g.Logger.Trace().
@@ -194,10 +534,275 @@ func (g *ReportGenerator) GenerateReport() *Report {
 			continue
 		}
 
+		entry.WhileOffline = g.StateRestored
+		entry.MissedHeights = missedHeights[address]
+		entry.MissRate, entry.MissRateKnown = missRate, missRateKnown
 		entries = append(entries, *entry)
 	}
 
-	g.State = newState
+	for address, oldState := range g.State {
+		if _, ok := newState[address]; ok {
+			continue
+		}
+
+		g.Logger.Info().Str("address", address).Msg("No new state present for address, validator is removed")
+		entries = append(entries, ReportEntry{
+			ValidatorAddress: oldState.Address,
+			ValidatorMoniker: oldState.Moniker,
+			Emoji:            RemovedEmoji,
+			Description:      RemovedDesc,
+			Direction:        REMOVED,
+			WhileOffline:     g.StateRestored,
+		})
+	}
+
+	g.SaveState(newState, height)
+
+	return g.NewReport(alerts, entries, newlyFailed, recovered)
+}
+
+// GetFailedValidatorsChanges remembers the validators which state could not be fetched
+// and returns the ones failing since this poll, and the ones which state was fetched again,
+// so a validator failing on each poll is only reported once.
+func (g *ReportGenerator) GetFailedValidatorsChanges(
+	failed []FailedValidator,
+	newState ValidatorsState,
+) ([]FailedValidator, []FailedValidator) {
+	failedValidators := make(map[string]FailedValidator, len(failed))
+	newlyFailed := []FailedValidator{}
+
+	for _, validator := range failed {
+		failedValidators[validator.Address] = validator
+
+		if _, ok := g.FailedValidators[validator.Address]; !ok {
+			newlyFailed = append(newlyFailed, validator)
+		}
+	}
+
+	recovered := []FailedValidator{}
+
+	for address, validator := range g.FailedValidators {
+		if _, ok := failedValidators[address]; ok {
+			continue
+		}
+
+		// The validators which are not there anymore are reported as removed instead.
+		if _, ok := newState[validator.ConsensusAddress]; ok {
+			recovered = append(recovered, validator)
+		}
+	}
+
+	g.FailedValidators = failedValidators
+
+	return newlyFailed, recovered
+}
+
+// GetMissRate returns the share of blocks the validator is missing. If tracking block
+// signatures, it's based on the blocks processed this poll, otherwise on how much the missed
+// blocks counter grew since the previous state, which cannot be less than 0.
+func (g *ReportGenerator) GetMissRate(
+	oldState, newState ValidatorState,
+	blocks []BlockSignatures,
+	missedHeights []int64,
+	height int64,
+) (float64, bool) {
+	if len(blocks) > 0 {
+		return float64(len(missedHeights)) / float64(len(blocks)), true
+	}
+
+	if height == 0 || g.StateHeight == 0 || height <= g.StateHeight {
+		return 0, false
+	}
+
+	missed := newState.MissedBlocks - oldState.MissedBlocks
+	if missed < 0 {
+		missed = 0
+	}
+
+	missRate := float64(missed) / float64(height-g.StateHeight)
+	if missRate > 1 {
+		missRate = 1
+	}
+
+	return missRate, true
+}
+
+// GetUnjailReadyReportEntry returns an entry once a validator jailed for downtime
+// can be unjailed, that is if its jail time ended since the previous state was taken,
+// so validators jailed long ago are not reported on each start. If UnjailReminderInterval
+// is set, then reminds about it every interval while the validator stays jailed.
+func (g *ReportGenerator) GetUnjailReadyReportEntry(state ValidatorState) (*ReportEntry, bool) {
+	if state.JailedForDoubleSign() || state.JailedUntil.IsZero() || time.Now().Before(state.JailedUntil) {
+		return nil, false
+	}
+
+	entry := ReportEntry{
+		ValidatorAddress: state.Address,
+		ValidatorMoniker: state.Moniker,
+		Emoji:            UnjailReadyEmoji,
+		Description:      UnjailReadyDesc,
+		Direction:        UNJAIL_READY,
+	}
+
+	if state.JailedUntil.After(g.StateTime) {
+		g.Logger.Debug().
+			Str("address", state.Address).
+			Msg("Validator can be unjailed")
+		g.UnjailReminders[state.Address] = time.Now()
+		return &entry, true
+	}
+
+	reminderInterval := time.Duration(g.Config.UnjailReminderInterval) * time.Second
+	if reminderInterval <= 0 {
+		return nil, false
+	}
+
+	// Reminders are not persisted, so after a restart waiting for a full interval
+	// instead of reminding about all the validators at once.
+	lastNotified, notified := g.UnjailReminders[state.Address]
+	if !notified {
+		g.UnjailReminders[state.Address] = time.Now()
+		return nil, false
+	}
+
+	if time.Since(lastNotified) < reminderInterval {
+		return nil, false
+	}
+
+	g.Logger.Debug().
+		Str("address", state.Address).
+		Msg("Validator can be unjailed, reminding")
+	g.UnjailReminders[state.Address] = time.Now()
+	entry.Description = fmt.Sprintf(UnjailReminderDesc, state.JailedUntil.UTC().Format(UnjailTimeFormat))
+
+	return &entry, true
+}
+
+// GetJailWarningReportEntry returns an entry when the estimated time to jail drops below
+// the next of the configured thresholds, regardless of the missed blocks group the validator is in.
+// Each threshold fires once per incident, which lasts until the validator is jailed
+// or has recovered: its missed blocks counter is back to zero, or the estimated time to jail
+// rose above the largest threshold. Polls without missed blocks don't end the incident,
+// so intermittently missing validators are not warned about the same thresholds again.
+func (g *ReportGenerator) GetJailWarningReportEntry(
+	address string,
+	oldState, newState ValidatorState,
+	missRate float64,
+	missRateKnown bool,
+) (*ReportEntry, bool) {
+	thresholds := g.Config.GetJailWarningThresholds()
+	if len(thresholds) == 0 || !g.Params.AvgBlockTimeKnown() {
+		return nil, false
+	}
+
+	entry := ReportEntry{
+		ValidatorAddress: newState.Address,
+		ValidatorMoniker: newState.Moniker,
+		Emoji:            JailWarningEmoji,
+		MissingBlocks:    newState.MissedBlocks,
+		Direction:        JAIL_WARNING,
+		MissRate:         missRate,
+		MissRateKnown:    missRateKnown,
+	}
+
+	// Without the miss rate, assuming the validator is missing all blocks while the counter grows.
+	timeToJail, approaching := entry.GetTimeToJailAtMissRate(g.Params)
+	if !missRateKnown {
+		timeToJail, approaching = entry.GetTimeToJail(g.Params), newState.MissedBlocks > oldState.MissedBlocks
+	}
+
+	if newState.Jailed || newState.MissedBlocks == 0 {
+		delete(g.JailWarnings, address)
+		return nil, false
+	}
+
+	if !approaching {
+		return nil, false
+	}
+
+	if timeToJail > thresholds[0] {
+		delete(g.JailWarnings, address)
+		return nil, false
+	}
+
+	fired := g.JailWarnings[address]
+	next := fired
+
+	for next < len(thresholds) && timeToJail <= thresholds[next] {
+		next++
+	}
+
+	if next == fired {
+		return nil, false
+	}
+
+	g.JailWarnings[address] = next
+	entry.Description = fmt.Sprintf(JailWarningDesc, FormatDuration(thresholds[next-1]))
+
+	g.Logger.Info().
+		Str("address", newState.Address).
+		Dur("timeToJail", timeToJail).
+		Msg("Validator is approaching jail")
+
+	return &entry, true
+}
+
+func (g *ReportGenerator) NewReport(
+	alerts []MonitoringAlert,
+	entries []ReportEntry,
+	failed []FailedValidator,
+	recovered []FailedValidator,
+) *Report {
+	return &Report{
+		ChainName:        g.Config.Name,
+		Time:             time.Now(),
+		ChainInfoConfig:  g.Config.ChainInfoConfig,
+		Params:           *g.Params,
+		GroupsCount:      len(g.Config.MissedBlocksGroups),
+		MonitoringAlerts: alerts,
+		Entries:          entries,
+		FailedValidators: failed,
+
+		RecoveredValidators: recovered,
+	}
+}
+
+// GetFailedPollAlerts counts the failed poll and returns the monitoring degraded alert
+// once FailedPollsThreshold polls in a row have failed, so the silence is not mistaken
+// for all the validators being fine.
+func (g *ReportGenerator) GetFailedPollAlerts(err error) []MonitoringAlert {
+	g.FailedPolls++
+
+	if g.MonitoringDegraded ||
+		g.Config.FailedPollsThreshold <= 0 ||
+		g.FailedPolls < g.Config.FailedPollsThreshold {
+		return nil
+	}
+
+	g.Logger.Warn().Int64("failedPolls", g.FailedPolls).Msg("Monitoring is degraded")
+	g.MonitoringDegraded = true
+
+	return []MonitoringAlert{{
+		Emoji:       MonitoringDegradedEmoji,
+		Description: fmt.Sprintf(MonitoringDegradedDesc, g.FailedPolls, err),
+	}}
+}
+
+// GetSuccessfulPollAlerts resets the failed polls counter and returns the recovery alert
+// if the monitoring degraded one was sent before.
+func (g *ReportGenerator) GetSuccessfulPollAlerts() []MonitoringAlert {
+	failedPolls := g.FailedPolls
+	g.FailedPolls = 0
+
+	if !g.MonitoringDegraded {
+		return nil
+	}
+
+	g.Logger.Info().Int64("failedPolls", failedPolls).Msg("Monitoring is recovered")
+	g.MonitoringDegraded = false
 
-	return &Report{Entries: entries}
+	return []MonitoringAlert{{
+		Emoji:       MonitoringRecoveredEmoji,
+		Description: fmt.Sprintf(MonitoringRecoveredDesc, failedPolls),
+	}}
 }
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestReportGenerator(config *ChainConfig, params *Params) *ReportGenerator {
+	logger := zerolog.Nop()
+	return NewReportGenerator(params, nil, nil, nil, nil, nil, config, &logger, nil)
+}
+
+func TestGetJailWarningReportEntry(t *testing.T) {
+	type step struct {
+		oldMissed     int64
+		newMissed     int64
+		jailed        bool
+		missRate      float64
+		missRateKnown bool
+		// Threshold the warning is expected for, empty if no warning is expected.
+		expected string
+	}
+
+	// At 6s per block and 1000 blocks to jail, each missed block is 6s closer to jail.
+	params := Params{
+		AvgBlockTime:        6,
+		AvgBlockTimeSamples: 100,
+		MissedBlocksToJail:  1000,
+	}
+
+	testCases := []struct {
+		name   string
+		params Params
+		steps  []step
+	}{
+		{
+			name:   "fires each threshold once",
+			params: params,
+			steps: []step{
+				{oldMissed: 0, newMissed: 100, expected: "2h"},
+				{oldMissed: 100, newMissed: 150},
+				{oldMissed: 150, newMissed: 500, expected: "1h"},
+				{oldMissed: 500, newMissed: 800, expected: "30m"},
+				{oldMissed: 800, newMissed: 900},
+			},
+		},
+		{
+			name:   "fires the lowest crossed threshold at once",
+			params: params,
+			steps: []step{
+				{oldMissed: 0, newMissed: 800, expected: "30m"},
+				{oldMissed: 800, newMissed: 850},
+			},
+		},
+		{
+			name:   "not approaching if the counter does not grow",
+			params: params,
+			steps: []step{
+				{oldMissed: 100, newMissed: 100},
+			},
+		},
+		{
+			name:   "polls without missed blocks do not end the incident",
+			params: params,
+			steps: []step{
+				{oldMissed: 0, newMissed: 100, expected: "2h"},
+				{oldMissed: 100, newMissed: 100},
+				{oldMissed: 100, newMissed: 110},
+			},
+		},
+		{
+			name:   "fires again once recovered",
+			params: params,
+			steps: []step{
+				{oldMissed: 0, newMissed: 100, expected: "2h"},
+				{oldMissed: 100, newMissed: 0},
+				{oldMissed: 0, newMissed: 100, expected: "2h"},
+			},
+		},
+		{
+			name:   "fires again once jailed",
+			params: params,
+			steps: []step{
+				{oldMissed: 0, newMissed: 800, expected: "30m"},
+				{oldMissed: 800, newMissed: 1000, jailed: true},
+				{oldMissed: 0, newMissed: 800, expected: "30m"},
+			},
+		},
+		{
+			name:   "uses the miss rate if known",
+			params: params,
+			steps: []step{
+				// 100 blocks left at 10% is 1000 blocks, so 1h40m, not 10m.
+				{oldMissed: 800, newMissed: 900, missRate: 0.1, missRateKnown: true, expected: "2h"},
+				{oldMissed: 900, newMissed: 910, missRate: 0.1, missRateKnown: true},
+			},
+		},
+		{
+			name:   "not approaching if not missing blocks at the moment",
+			params: params,
+			steps: []step{
+				{oldMissed: 900, newMissed: 900, missRate: 0, missRateKnown: true},
+			},
+		},
+		{
+			name: "disabled if the block time is not known",
+			params: Params{
+				MissedBlocksToJail: 1000,
+			},
+			steps: []step{
+				{oldMissed: 0, newMissed: 900},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			params := testCase.params
+			config := &ChainConfig{
+				jailWarningThresholds: []time.Duration{2 * time.Hour, time.Hour, 30 * time.Minute},
+			}
+			generator := newTestReportGenerator(config, &params)
+
+			for index, step := range testCase.steps {
+				oldState := ValidatorState{Address: "validator", MissedBlocks: step.oldMissed}
+				newState := ValidatorState{Address: "validator", MissedBlocks: step.newMissed, Jailed: step.jailed}
+
+				entry, present := generator.GetJailWarningReportEntry(
+					"consensus",
+					oldState,
+					newState,
+					step.missRate,
+					step.missRateKnown,
+				)
+
+				if step.expected == "" {
+					if present {
+						t.Errorf("step %d: expected no entry, got %q", index, entry.Description)
+					}
+					continue
+				}
+
+				if !present {
+					t.Errorf("step %d: expected a warning for %s, got none", index, step.expected)
+					continue
+				}
+
+				expectedDesc := fmt.Sprintf(JailWarningDesc, step.expected)
+				if entry.Description != expectedDesc || entry.Direction != JAIL_WARNING {
+					t.Errorf("step %d: expected %q, got %q", index, expectedDesc, entry.Description)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMissedBlocksStreaksReportEntries(t *testing.T) {
+	type expectedEntry struct {
+		direction   Direction
+		description string
+	}
+
+	// Blocks signed by the validator or not, from the first to the last one.
+	newBlocks := func(signed ...bool) []BlockSignatures {
+		blocks := make([]BlockSignatures, len(signed))
+		for index, isSigned := range signed {
+			blocks[index] = BlockSignatures{
+				Height:  int64(index + 1),
+				Signers: map[string]bool{"consensus": isSigned},
+			}
+		}
+
+		return blocks
+	}
+
+	testCases := []struct {
+		name            string
+		validator       ValidatorState
+		config          MissedBlocksStreakConfig
+		streaks         map[string]int64
+		blocks          []BlockSignatures
+		expected        []expectedEntry
+		expectedStreaks map[string]int64
+	}{
+		{
+			name:            "alerts once the threshold is reached",
+			validator:       ValidatorState{Address: "validator", Active: true},
+			config:          MissedBlocksStreakConfig{Threshold: 3},
+			streaks:         map[string]int64{},
+			blocks:          newBlocks(false, false, false, false, false),
+			expected:        []expectedEntry{{OFFLINE, fmt.Sprintf(OfflineDesc, 3)}},
+			expectedStreaks: map[string]int64{"consensus": 5},
+		},
+		{
+			name:            "continues the streak from the previous polls",
+			validator:       ValidatorState{Address: "validator", Active: true},
+			config:          MissedBlocksStreakConfig{Threshold: 3},
+			streaks:         map[string]int64{"consensus": 2},
+			blocks:          newBlocks(false),
+			expected:        []expectedEntry{{OFFLINE, fmt.Sprintf(OfflineDesc, 3)}},
+			expectedStreaks: map[string]int64{"consensus": 3},
+		},
+		{
+			name:      "alerts once signing again",
+			validator: ValidatorState{Address: "validator", Active: true},
+			config:    MissedBlocksStreakConfig{Threshold: 3},
+			streaks:   map[string]int64{},
+			blocks:    newBlocks(false, false, false, false, true, false),
+			expected: []expectedEntry{
+				{OFFLINE, fmt.Sprintf(OfflineDesc, 3)},
+				{ONLINE, fmt.Sprintf(OnlineDesc, 4)},
+			},
+			expectedStreaks: map[string]int64{"consensus": 1},
+		},
+		{
+			name:            "no alerts for a streak below the threshold",
+			validator:       ValidatorState{Address: "validator", Active: true},
+			config:          MissedBlocksStreakConfig{Threshold: 3},
+			streaks:         map[string]int64{},
+			blocks:          newBlocks(false, false, true),
+			expected:        []expectedEntry{},
+			expectedStreaks: map[string]int64{},
+		},
+		{
+			name:            "ignores inactive validators",
+			validator:       ValidatorState{Address: "validator"},
+			config:          MissedBlocksStreakConfig{Threshold: 3},
+			streaks:         map[string]int64{},
+			blocks:          newBlocks(false, false, false),
+			expected:        []expectedEntry{},
+			expectedStreaks: map[string]int64{},
+		},
+		{
+			name:      "uses the validator threshold",
+			validator: ValidatorState{Address: "validator", Active: true},
+			config: MissedBlocksStreakConfig{
+				Threshold:  3,
+				Validators: map[string]int64{"validator": 0},
+			},
+			streaks:         map[string]int64{},
+			blocks:          newBlocks(false, false, false),
+			expected:        []expectedEntry{},
+			expectedStreaks: map[string]int64{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := &ChainConfig{MissedBlocksStreakConfig: testCase.config}
+			generator := newTestReportGenerator(config, &Params{})
+			generator.MissedBlocksStreaks = testCase.streaks
+
+			entries := generator.GetMissedBlocksStreaksReportEntries(
+				testCase.blocks,
+				ValidatorsState{"consensus": testCase.validator},
+			)
+
+			actual := make([]expectedEntry, len(entries))
+			for index, entry := range entries {
+				actual[index] = expectedEntry{entry.Direction, entry.Description}
+			}
+
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected entries %+v, got %+v", testCase.expected, actual)
+			}
+
+			if !reflect.DeepEqual(generator.MissedBlocksStreaks, testCase.expectedStreaks) {
+				t.Errorf("expected streaks %v, got %v", testCase.expectedStreaks, generator.MissedBlocksStreaks)
+			}
+		})
+	}
+}
+
+func TestGetFailedValidatorsChanges(t *testing.T) {
+	first := FailedValidator{Address: "first", ConsensusAddress: "first-consensus"}
+	second := FailedValidator{Address: "second", ConsensusAddress: "second-consensus"}
+
+	testCases := []struct {
+		name              string
+		previous          []FailedValidator
+		failed            []FailedValidator
+		newState          ValidatorsState
+		expectedFailed    []FailedValidator
+		expectedRecovered []FailedValidator
+	}{
+		{
+			name:              "reports newly failed validators",
+			failed:            []FailedValidator{first},
+			expectedFailed:    []FailedValidator{first},
+			expectedRecovered: []FailedValidator{},
+		},
+		{
+			name:              "does not report the validators failing again",
+			previous:          []FailedValidator{first},
+			failed:            []FailedValidator{first, second},
+			expectedFailed:    []FailedValidator{second},
+			expectedRecovered: []FailedValidator{},
+		},
+		{
+			name:              "reports recovered validators",
+			previous:          []FailedValidator{first, second},
+			failed:            []FailedValidator{second},
+			newState:          ValidatorsState{"first-consensus": {Address: "first"}},
+			expectedFailed:    []FailedValidator{},
+			expectedRecovered: []FailedValidator{first},
+		},
+		{
+			name:              "does not report removed validators as recovered",
+			previous:          []FailedValidator{first},
+			expectedFailed:    []FailedValidator{},
+			expectedRecovered: []FailedValidator{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			generator := newTestReportGenerator(&ChainConfig{}, &Params{})
+			generator.GetFailedValidatorsChanges(testCase.previous, ValidatorsState{})
+
+			failed, recovered := generator.GetFailedValidatorsChanges(testCase.failed, testCase.newState)
+
+			if !reflect.DeepEqual(failed, testCase.expectedFailed) {
+				t.Errorf("expected failed %+v, got %+v", testCase.expectedFailed, failed)
+			}
+
+			if !reflect.DeepEqual(recovered, testCase.expectedRecovered) {
+				t.Errorf("expected recovered %+v, got %+v", testCase.expectedRecovered, recovered)
+			}
+		})
+	}
+}
@@ -1,60 +1,29 @@
 package main
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
 )
 
 type SlackReporter struct {
-	ChainInfoConfig ChainInfoConfig
-	SlackConfig     SlackConfig
-	Params          *Params
-	Logger          zerolog.Logger
+	SlackConfig SlackConfig
+	Logger      zerolog.Logger
 
 	SlackClient slack.Client
 }
 
 func NewSlackReporter(
-	chainInfoConfig ChainInfoConfig,
 	slackConfig SlackConfig,
-	params *Params,
 	logger *zerolog.Logger,
 ) *SlackReporter {
 	return &SlackReporter{
-		ChainInfoConfig: chainInfoConfig,
-		SlackConfig:     slackConfig,
-		Params:          params,
-		Logger:          logger.With().Str("component", "slack_reporter").Logger(),
+		SlackConfig: slackConfig,
+		Logger:      logger.With().Str("component", "slack_reporter").Logger(),
 	}
 }
 
 func (r SlackReporter) Serialize(report Report) string {
-	var sb strings.Builder
-
-	for _, entry := range report.Entries {
-		var (
-			validatorLink string
-			timeToJail    = ""
-		)
-
-		if entry.Direction == INCREASING {
-			timeToJail = fmt.Sprintf(" (%s till jail)", entry.GetTimeToJail(r.Params))
-		}
-
-		validatorLink = r.ChainInfoConfig.GetValidatorPage(entry.ValidatorAddress, entry.ValidatorMoniker)
-		sb.WriteString(fmt.Sprintf(
-			"%s <strong>%s %s</strong>%s\n",
-			entry.Emoji,
-			validatorLink,
-			entry.Description,
-			timeToJail,
-		))
-	}
-
-	return sb.String()
+	return report.SerializeHTML(nil)
 }
 
 func (r *SlackReporter) Init() {
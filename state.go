@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog"
+)
+
+type StateSnapshot struct {
+	Height int64
+	Time   time.Time
+	State  ValidatorsState
+	// Last block which signatures were processed and the missed blocks streaks as of it,
+	// if tracking block signatures, so the blocks produced during a restart are not skipped.
+	LastBlockHeight     int64
+	MissedBlocksStreaks map[string]int64
+}
+
+type StateStore struct {
+	Path   string
+	Logger zerolog.Logger
+}
+
+func NewStateStore(path string, logger *zerolog.Logger) *StateStore {
+	return &StateStore{
+		Path:   path,
+		Logger: logger.With().Str("component", "state_store").Logger(),
+	}
+}
+
+func (s *StateStore) Enabled() bool {
+	return s.Path != ""
+}
+
+// Load returns the previously saved snapshot, or nil if there's none.
+func (s *StateStore) Load() (*StateSnapshot, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	bytes, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		s.Logger.Info().Str("path", s.Path).Msg("State file does not exist, starting from scratch.")
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshot StateSnapshot
+	if _, err := toml.Decode(string(bytes), &snapshot); err != nil {
+		return nil, err
+	}
+
+	s.Logger.Debug().
+		Int64("height", snapshot.Height).
+		Time("time", snapshot.Time).
+		Int("validators", len(snapshot.State)).
+		Msg("State is loaded successfully.")
+	return &snapshot, nil
+}
+
+// Save writes the snapshot into a temporary file and then renames it,
+// so a crash while writing does not leave a corrupted state file.
+func (s *StateStore) Save(snapshot StateSnapshot) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	tmpPath := s.Path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := toml.NewEncoder(f).Encode(snapshot); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, s.Path); err != nil {
+		return err
+	}
+
+	s.Logger.Trace().Int64("height", snapshot.Height).Msg("State is saved successfully.")
+	return nil
+}
@@ -18,11 +18,8 @@ import (
 const MaxMessageSize = 4096
 
 type TelegramReporter struct {
-	ChainInfoConfig   ChainInfoConfig
 	TelegramAppConfig TelegramAppConfig
-	AppConfig         *AppConfig
-	Params            *Params
-	Client            *TendermintGRPC
+	Chains            []*Chain
 	Logger            zerolog.Logger
 
 	TelegramConfig TelegramConfig
@@ -39,19 +36,13 @@ type TelegramConfig struct {
 }
 
 func NewTelegramReporter(
-	chainInfoConfig ChainInfoConfig,
 	telegramAppConfig TelegramAppConfig,
-	appConfig *AppConfig,
-	params *Params,
-	client *TendermintGRPC,
+	chains []*Chain,
 	logger *zerolog.Logger,
 ) *TelegramReporter {
 	return &TelegramReporter{
-		ChainInfoConfig:   chainInfoConfig,
 		TelegramAppConfig: telegramAppConfig,
-		AppConfig:         appConfig,
-		Params:            params,
-		Client:            client,
+		Chains:            chains,
 		Logger:            logger.With().Str("component", "telegram_reporter").Logger(),
 	}
 }
@@ -122,32 +113,9 @@ func (c *TelegramConfig) getNotifiersSerialized(address string) string {
 }
 
 func (r TelegramReporter) Serialize(report Report) string {
-	var sb strings.Builder
-
-	for _, entry := range report.Entries {
-		var (
-			validatorLink string
-			timeToJail    = ""
-		)
-
-		if entry.Direction == INCREASING {
-			timeToJail = fmt.Sprintf(" (%s till jail)", entry.GetTimeToJail(r.Params))
-		}
-
-		validatorLink = r.ChainInfoConfig.GetValidatorPage(entry.ValidatorAddress, entry.ValidatorMoniker)
-		notifiers := r.TelegramConfig.getNotifiersSerialized(entry.ValidatorAddress)
-
-		sb.WriteString(fmt.Sprintf(
-			"%s <strong>%s %s</strong>%s %s\n",
-			entry.Emoji,
-			validatorLink,
-			html.EscapeString(entry.Description),
-			timeToJail,
-			notifiers,
-		))
-	}
-
-	return sb.String()
+	return report.SerializeHTML(func(entry ReportEntry) string {
+		return " " + r.TelegramConfig.getNotifiersSerialized(entry.ValidatorAddress)
+	})
 }
 
 func (r *TelegramReporter) Init() {
@@ -181,7 +149,10 @@ func (r *TelegramReporter) Init() {
 	r.TelegramBot.Handle("/params", r.getChainParams)
 	go r.TelegramBot.Start()
 
-	r.loadConfigFromYaml()
+	// Without the subscriptions config, notifications would go to nobody.
+	if err := r.loadConfigFromYaml(); err != nil {
+		r.Logger.Fatal().Err(err).Msg("Could not load Telegram config!")
+	}
 }
 
 func (r TelegramReporter) Enabled() bool {
@@ -252,16 +223,17 @@ func (r TelegramReporter) sendMessage(message *tb.Message, text string) {
 func (r TelegramReporter) getHelp(message *tb.Message) {
 	var sb strings.Builder
 	sb.WriteString("<strong>missed-block-checker</strong>\n\n")
-	sb.WriteString(fmt.Sprintf("Query for the %s network info.\n", r.ChainInfoConfig.MintscanPrefix))
+	sb.WriteString(fmt.Sprintf("Query for the %s network(s) info.\n", strings.Join(r.getChainsNames(), ", ")))
 	sb.WriteString("Can understand the following commands:\n")
 	sb.WriteString("- /subscribe &lt;validator address&gt; - be notified on validator's missed block in a Telegram channel\n")
 	sb.WriteString("- /unsubscribe &lt;validator address&gt; - undo the subscription given at the previous step\n")
 	sb.WriteString("- /status &lt;validator address&gt; - get validator missed blocks\n")
 	sb.WriteString("- /status - get the missed blocks of the validator(s) you're subscribed to\n\n")
-	sb.WriteString("- /config - display bot config\n")
-	sb.WriteString("- /params - display chain slashing params\n")
-	sb.WriteString("- /validators - display all active validators and their missed blocks\n")
-	sb.WriteString("- /missing - display only validators missing blocks above threshold and their missing blocks\n")
+	sb.WriteString("- /config [chain] - display bot config\n")
+	sb.WriteString("- /params [chain] - display chain slashing params\n")
+	sb.WriteString("- /validators [chain] - display all active validators and their missed blocks\n")
+	sb.WriteString("- /missing [chain] - display only validators missing blocks above threshold and their missing blocks\n")
+	sb.WriteString("If the chain is not specified, commands display the info for all the chains.\n")
 	sb.WriteString("Created by <a href=\"https://freak12techno.github.io\">freak12techno</a> at <a href=\"https://validator.solar\">SOLAR Labs</a> with ❤️.\n")
 	sb.WriteString("This bot is open-sourced, you can get the source code at https://github.com/solarlabsteam/missed-blocks-checker.\n\n")
 	sb.WriteString("We also maintain the following tools for Cosmos ecosystem:\n")
@@ -287,7 +259,13 @@ func (r *TelegramReporter) getValidatorStatus(message *tb.Message) {
 	address := args[1]
 	r.Logger.Debug().Str("address", address).Msg("getValidatorStatus: address")
 
-	state, err := r.Client.GetValidatorState(address)
+	chain, err := FindChainByValidatorAddress(r.Chains, address)
+	if err != nil {
+		r.sendMessage(message, "Could not find a chain for this validator")
+		return
+	}
+
+	state, err := chain.GRPC.GetValidatorState(address)
 	if err != nil {
 		r.Logger.Error().
 			Str("address", address).
@@ -297,7 +275,7 @@ func (r *TelegramReporter) getValidatorStatus(message *tb.Message) {
 		return
 	}
 
-	r.sendMessage(message, r.getValidatorWithMissedBlocksSerialized(state))
+	r.sendMessage(message, r.getValidatorWithMissedBlocksSerialized(chain, state))
 	r.Logger.Info().
 		Str("user", message.Sender.Username).
 		Str("address", address).
@@ -305,18 +283,44 @@ func (r *TelegramReporter) getValidatorStatus(message *tb.Message) {
 }
 
 func (r *TelegramReporter) getValidatorsStatus(message *tb.Message, getOnlyMissing bool) {
-	state, err := r.Client.GetValidatorsState()
+	chains, err := r.getChainsFromArgs(message)
+	if err != nil {
+		r.sendMessage(message, err.Error())
+		return
+	}
+
+	var sb strings.Builder
+
+	for _, chain := range chains {
+		sendMessage, err := r.getChainValidatorsStatusSerialized(chain, getOnlyMissing)
+		if err != nil {
+			r.sendMessage(message, sendMessage)
+			return
+		}
+
+		sb.WriteString(r.getChainHeader(chain))
+		sb.WriteString(sendMessage)
+	}
+
+	r.sendMessage(message, sb.String())
+	r.Logger.Info().
+		Str("user", message.Sender.Username).
+		Msg("Successfully returned validators status")
+}
+
+func (r *TelegramReporter) getChainValidatorsStatusSerialized(chain *Chain, getOnlyMissing bool) (string, error) {
+	state, failed, err := chain.GRPC.GetValidatorsState()
 	if err != nil {
 		r.Logger.Error().
 			Err(err).
+			Str("chain", chain.Config.Name).
 			Msg("Could not get validators state")
-		r.sendMessage(message, "Could not get validators state")
-		return
+		return "Could not get validators state", err
 	}
 
 	state = FilterMap(state, func(s ValidatorState) bool {
 		if getOnlyMissing {
-			group, err := r.AppConfig.MissedBlocksGroups.GetGroup(s.MissedBlocks)
+			group, err := chain.GetMissedBlocksGroups().GetGroup(s.MissedBlocks)
 			if err != nil {
 				r.Logger.Error().
 					Err(err).
@@ -335,26 +339,43 @@ func (r *TelegramReporter) getValidatorsStatus(message *tb.Message, getOnlyMissi
 		return stateArray[i].MissedBlocks < stateArray[j].MissedBlocks
 	})
 
-	sendMessage, err := r.getValidatorsWithMissedBlocksSerialized(stateArray)
+	sendMessage, err := r.getValidatorsWithMissedBlocksSerialized(chain, stateArray)
 	if err != nil {
 		r.Logger.Error().
 			Err(err).
 			Msg("Error serializing validators")
-		r.sendMessage(message, "Error serializing response")
-		return
+		return "Error serializing response", err
 	}
 
-	r.sendMessage(message, sendMessage)
-	r.Logger.Info().
-		Str("user", message.Sender.Username).
-		Msg("Successfully returned validators status")
+	if len(failed) > 0 {
+		sendMessage += fmt.Sprintf("<i>Could not fetch the state of %d validator(s).</i>\n", len(failed))
+	}
+
+	return sendMessage, nil
 }
 
 func (r *TelegramReporter) getChainParams(message *tb.Message) {
-	params := r.Client.GetSlashingParams()
-	sendMessage := r.getChainParamsSerialized(params, r.Params)
+	chains, err := r.getChainsFromArgs(message)
+	if err != nil {
+		r.sendMessage(message, err.Error())
+		return
+	}
+
+	var sb strings.Builder
+
+	for _, chain := range chains {
+		params, err := chain.GRPC.GetSlashingParams()
+		if err != nil {
+			r.sendMessage(message, "Could not query chain params")
+			return
+		}
 
-	r.sendMessage(message, sendMessage)
+		sb.WriteString(r.getChainHeader(chain))
+		chainParams := chain.GetParams()
+		sb.WriteString(r.getChainParamsSerialized(params, &chainParams))
+	}
+
+	r.sendMessage(message, sb.String())
 	r.Logger.Info().
 		Str("user", message.Sender.Username).
 		Msg("Successfully returned validators status")
@@ -372,7 +393,15 @@ func (r *TelegramReporter) getSubscribedValidatorsStatuses(message *tb.Message)
 	var sb strings.Builder
 
 	for _, address := range subscribedValidators {
-		state, err := r.Client.GetValidatorState(address)
+		chain, err := FindChainByValidatorAddress(r.Chains, address)
+		if err != nil {
+			r.Logger.Warn().
+				Str("address", address).
+				Msg("Could not find a chain for subscribed validator")
+			continue
+		}
+
+		state, err := chain.GRPC.GetValidatorState(address)
 		if err != nil {
 			r.Logger.Error().
 				Str("address", address).
@@ -382,7 +411,7 @@ func (r *TelegramReporter) getSubscribedValidatorsStatuses(message *tb.Message)
 			return
 		}
 
-		sb.WriteString(r.getValidatorWithMissedBlocksSerialized(state))
+		sb.WriteString(r.getValidatorWithMissedBlocksSerialized(chain, state))
 		sb.WriteString("\n")
 	}
 
@@ -392,25 +421,30 @@ func (r *TelegramReporter) getSubscribedValidatorsStatuses(message *tb.Message)
 		Msg("Successfully returned subscribed validator statuses")
 }
 
-func (r *TelegramReporter) getValidatorWithMissedBlocksSerialized(state ValidatorState) string {
+func (r *TelegramReporter) getValidatorWithMissedBlocksSerialized(chain *Chain, state ValidatorState) string {
+	params := chain.GetParams()
+
 	var sb strings.Builder
-	sb.WriteString(r.ChainInfoConfig.GetValidatorPage(state.Address, state.Moniker) + "\n")
+	sb.WriteString(chain.Config.ChainInfoConfig.GetValidatorPage(state.Address, state.Moniker) + "\n")
 	sb.WriteString(fmt.Sprintf(
 		"Missed blocks: %d/%d (%.2f%%)\n",
 		state.MissedBlocks,
-		r.Params.SignedBlocksWindow,
-		float64(state.MissedBlocks)/float64(r.Params.SignedBlocksWindow)*100,
+		params.SignedBlocksWindow,
+		float64(state.MissedBlocks)/float64(params.SignedBlocksWindow)*100,
 	))
 
 	return sb.String()
 }
 
-func (r *TelegramReporter) getValidatorsWithMissedBlocksSerialized(state []ValidatorState) (string, error) {
+func (r *TelegramReporter) getValidatorsWithMissedBlocksSerialized(
+	chain *Chain,
+	state []ValidatorState,
+) (string, error) {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("<strong>Total validators:</strong> %d\n", len(state)))
 
 	for _, validator := range state {
-		group, err := r.AppConfig.MissedBlocksGroups.GetGroup(validator.MissedBlocks)
+		group, err := chain.GetMissedBlocksGroups().GetGroup(validator.MissedBlocks)
 		if err != nil {
 			return "", err
 		}
@@ -418,8 +452,8 @@ func (r *TelegramReporter) getValidatorsWithMissedBlocksSerialized(state []Valid
 		sb.WriteString(fmt.Sprintf(
 			"%s %s (%.2f%%)\n",
 			group.EmojiEnd,
-			r.ChainInfoConfig.GetValidatorPage(validator.Address, validator.Moniker),
-			float64(validator.MissedBlocks)/float64(r.Params.SignedBlocksWindow)*100,
+			chain.Config.ChainInfoConfig.GetValidatorPage(validator.Address, validator.Moniker),
+			float64(validator.MissedBlocks)/float64(chain.GetParams().SignedBlocksWindow)*100,
 		))
 	}
 
@@ -449,9 +483,16 @@ func (r *TelegramReporter) getChainParamsSerialized(
 		"<strong>Slashing factor for double sign:</strong> %.2f%%\n",
 		slashingParams.SlashFractionDoubleSign*100,
 	))
+
+	if !params.AvgBlockTimeKnown() {
+		sb.WriteString("<strong>Average block time:</strong> not known yet\n")
+		return sb.String()
+	}
+
 	sb.WriteString(fmt.Sprintf(
-		"<strong>Average block time:</strong> %.2f seconds\n",
+		"<strong>Average block time:</strong> %.2f seconds (estimated over %d blocks)\n",
 		params.AvgBlockTime,
+		params.AvgBlockTimeSamples,
 	))
 	sb.WriteString(fmt.Sprintf(
 		"<strong>Approximate time to go to jail when missing all blocks:</strong> %s\n",
@@ -476,7 +517,13 @@ func (r *TelegramReporter) subscribeToValidatorUpdates(message *tb.Message) {
 	address := args[1]
 	r.Logger.Debug().Str("address", address).Msg("subscribeToValidatorUpdates: address")
 
-	validator, err := r.Client.GetValidator(address)
+	chain, err := FindChainByValidatorAddress(r.Chains, address)
+	if err != nil {
+		r.sendMessage(message, "Could not find a chain for this validator")
+		return
+	}
+
+	validator, err := chain.GRPC.GetValidator(address)
 	if err != nil {
 		r.Logger.Error().
 			Str("address", address).
@@ -486,18 +533,19 @@ func (r *TelegramReporter) subscribeToValidatorUpdates(message *tb.Message) {
 		return
 	}
 
-	err = r.TelegramConfig.addNotifier(address, message.Sender.Username)
-	r.saveYamlConfig()
-
-	if err != nil {
+	if err := r.TelegramConfig.addNotifier(address, message.Sender.Username); err != nil {
 		r.sendMessage(message, err.Error())
-		r.saveYamlConfig()
 		return
 	}
 
+	if err := r.saveYamlConfig(); err != nil {
+		r.Logger.Error().Err(err).Msg("Could not save Telegram config")
+		r.sendMessage(message, "Could not save the subscription, it will be lost on restart")
+	}
+
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Subscribed to the notification of <code>%s</code> ", validator.Description.Moniker))
-	sb.WriteString(r.ChainInfoConfig.GetValidatorPage(validator.OperatorAddress, "Explorer"))
+	sb.WriteString(chain.Config.ChainInfoConfig.GetValidatorPage(validator.OperatorAddress, "Explorer"))
 
 	r.sendMessage(message, sb.String())
 	r.Logger.Info().
@@ -521,7 +569,13 @@ func (r *TelegramReporter) unsubscribeFromValidatorUpdates(message *tb.Message)
 	address := args[1]
 	r.Logger.Debug().Str("address", address).Msg("unsubscribeFromValidatorUpdates: address")
 
-	validator, err := r.Client.GetValidator(address)
+	chain, err := FindChainByValidatorAddress(r.Chains, address)
+	if err != nil {
+		r.sendMessage(message, "Could not find a chain for this validator")
+		return
+	}
+
+	validator, err := chain.GRPC.GetValidator(address)
 	if err != nil {
 		r.Logger.Error().
 			Str("address", address).
@@ -531,18 +585,19 @@ func (r *TelegramReporter) unsubscribeFromValidatorUpdates(message *tb.Message)
 		return
 	}
 
-	err = r.TelegramConfig.removeNotifier(address, message.Sender.Username)
-	r.saveYamlConfig()
-
-	if err != nil {
+	if err := r.TelegramConfig.removeNotifier(address, message.Sender.Username); err != nil {
 		r.sendMessage(message, err.Error())
-		r.saveYamlConfig()
 		return
 	}
 
+	if err := r.saveYamlConfig(); err != nil {
+		r.Logger.Error().Err(err).Msg("Could not save Telegram config")
+		r.sendMessage(message, "Could not save the unsubscription, it will be lost on restart")
+	}
+
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Unsubscribed from the notification of <code>%s</code> ", validator.Description.Moniker))
-	sb.WriteString(r.ChainInfoConfig.GetValidatorPage(validator.OperatorAddress, "Explorer"))
+	sb.WriteString(chain.Config.ChainInfoConfig.GetValidatorPage(validator.OperatorAddress, "Explorer"))
 
 	r.sendMessage(message, sb.String())
 	r.Logger.Info().
@@ -552,67 +607,136 @@ func (r *TelegramReporter) unsubscribeFromValidatorUpdates(message *tb.Message)
 }
 
 func (r *TelegramReporter) displayConfig(message *tb.Message) {
+	chains, err := r.getChainsFromArgs(message)
+	if err != nil {
+		r.sendMessage(message, err.Error())
+		return
+	}
+
 	var sb strings.Builder
 
-	if len(r.AppConfig.ExcludeValidators) == 0 && len(r.AppConfig.IncludeValidators) == 0 {
+	for _, chain := range chains {
+		sb.WriteString(r.getChainHeader(chain))
+		sb.WriteString(r.getChainConfigSerialized(chain))
+	}
+
+	r.sendMessage(message, sb.String())
+}
+
+func (r *TelegramReporter) getChainConfigSerialized(chain *Chain) string {
+	config := chain.Config
+
+	var sb strings.Builder
+
+	if len(config.ExcludeValidators) == 0 && len(config.IncludeValidators) == 0 {
 		sb.WriteString("<strong>Monitoring all validators.\n</strong>")
-	} else if len(r.AppConfig.IncludeValidators) == 0 {
+	} else if len(config.IncludeValidators) == 0 {
 		sb.WriteString("<strong>Monitoring all validators, except the following ones:\n</strong>")
 
-		for _, validator := range r.AppConfig.ExcludeValidators {
-			sb.WriteString(" - " + r.ChainInfoConfig.GetValidatorPage(validator, validator) + "\n")
+		for _, validator := range config.ExcludeValidators {
+			sb.WriteString(" - " + config.ChainInfoConfig.GetValidatorPage(validator, validator) + "\n")
 		}
-	} else if len(r.AppConfig.ExcludeValidators) == 0 {
+	} else if len(config.ExcludeValidators) == 0 {
 		sb.WriteString("<strong>Monitoring the following validators:\n</strong>")
 
-		for _, validator := range r.AppConfig.IncludeValidators {
-			sb.WriteString("- " + r.ChainInfoConfig.GetValidatorPage(validator, validator) + "\n")
+		for _, validator := range config.IncludeValidators {
+			sb.WriteString("- " + config.ChainInfoConfig.GetValidatorPage(validator, validator) + "\n")
 		}
 	}
 
 	sb.WriteString("<strong>Missed blocks thresholds:\n</strong>")
-	for _, group := range r.AppConfig.MissedBlocksGroups {
+	for _, group := range chain.GetMissedBlocksGroups() {
 		sb.WriteString(fmt.Sprintf("%s %d - %d\n", group.EmojiStart, group.Start, group.End))
 	}
 
-	r.sendMessage(message, sb.String())
+	return sb.String()
+}
+
+// getChainsFromArgs returns the chain which name is passed as a command argument,
+// or all the chains if it's not passed.
+func (r *TelegramReporter) getChainsFromArgs(message *tb.Message) ([]*Chain, error) {
+	args := strings.SplitAfterN(message.Text, " ", 2)
+	if len(args) < 2 {
+		return r.Chains, nil
+	}
+
+	chain, err := FindChainByName(r.Chains, strings.TrimSpace(args[1]))
+	if err != nil {
+		return nil, fmt.Errorf("Could not find chain, available chains: %s", strings.Join(r.getChainsNames(), ", ")) //nolint
+	}
+
+	return []*Chain{chain}, nil
+}
+
+func (r *TelegramReporter) getChainsNames() []string {
+	names := make([]string, len(r.Chains))
+	for index, chain := range r.Chains {
+		names[index] = chain.Config.GetName()
+	}
+
+	return names
+}
+
+// getChainHeader returns the chain name header if there are multiple chains,
+// so it's clear which chain the info is about.
+func (r *TelegramReporter) getChainHeader(chain *Chain) string {
+	if len(r.Chains) <= 1 {
+		return ""
+	}
+
+	return fmt.Sprintf("\n<strong>%s</strong>\n", html.EscapeString(chain.Config.GetName()))
 }
 
-func (r *TelegramReporter) loadConfigFromYaml() {
+func (r *TelegramReporter) loadConfigFromYaml() error {
 	if _, err := os.Stat(r.TelegramAppConfig.ConfigPath); os.IsNotExist(err) {
 		r.Logger.Info().Str("path", r.TelegramAppConfig.ConfigPath).Msg("Telegram config file does not exist, creating.")
-		if _, err = os.Create(r.TelegramAppConfig.ConfigPath); err != nil {
-			r.Logger.Fatal().Err(err).Msg("Could not create Telegram config!")
+		f, err := os.Create(r.TelegramAppConfig.ConfigPath)
+		if err != nil {
+			return fmt.Errorf("could not create Telegram config: %w", err)
 		}
+		f.Close()
 	} else if err != nil {
-		r.Logger.Fatal().Err(err).Msg("Could not fetch Telegram config!")
+		return fmt.Errorf("could not fetch Telegram config: %w", err)
 	}
 
 	bytes, err := os.ReadFile(r.TelegramAppConfig.ConfigPath)
 	if err != nil {
-		r.Logger.Fatal().Err(err).Msg("Could not read Telegram config!")
+		return fmt.Errorf("could not read Telegram config: %w", err)
 	}
 
 	var conf TelegramConfig
 	if _, err := toml.Decode(string(bytes), &conf); err != nil {
-		r.Logger.Fatal().Err(err).Msg("Could not load Telegram config!")
+		return fmt.Errorf("could not parse Telegram config: %w", err)
 	}
 
 	r.TelegramConfig = conf
 	r.Logger.Debug().Msg("Telegram config is loaded successfully.")
+	return nil
 }
 
-func (r *TelegramReporter) saveYamlConfig() {
-	f, err := os.Create(r.TelegramAppConfig.ConfigPath)
+// saveYamlConfig writes the config to a temporary file first, so a failed write,
+// for example when the disk is full, does not wipe the existing subscriptions.
+func (r *TelegramReporter) saveYamlConfig() error {
+	tmpPath := r.TelegramAppConfig.ConfigPath + ".tmp"
+
+	f, err := os.Create(tmpPath)
 	if err != nil {
-		r.Logger.Fatal().Err(err).Msg("Could not open Telegram config when saving")
+		return err
 	}
+
 	if err := toml.NewEncoder(f).Encode(r.TelegramConfig); err != nil {
-		r.Logger.Fatal().Err(err).Msg("Could not save Telegram config")
+		f.Close()
+		return err
 	}
+
 	if err := f.Close(); err != nil {
-		r.Logger.Fatal().Err(err).Msg("Could not close Telegram config when saving")
+		return err
+	}
+
+	if err := os.Rename(tmpPath, r.TelegramAppConfig.ConfigPath); err != nil {
+		return err
 	}
 
 	r.Logger.Debug().Msg("Telegram config is updated successfully.")
+	return nil
 }
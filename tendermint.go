@@ -2,48 +2,166 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/rs/zerolog"
 	tmrpc "github.com/tendermint/tendermint/rpc/client/http"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
 	ctypes "github.com/tendermint/tendermint/types"
 )
 
+const RPCHealthCheckTimeout = 10 * time.Second
+
 type TendermintRPC struct {
 	NodeConfig          NodeConfig
+	Nodes               *NodesPool
+	ConsensusNodePrefix string
 	BlocksDiffInThePast int64
 	Logger              zerolog.Logger
 }
 
-func NewTendermintRPC(nodeConfig NodeConfig, logger *zerolog.Logger) *TendermintRPC {
-	return &TendermintRPC{
+func NewTendermintRPC(nodeConfig NodeConfig, consensusNodePrefix string, logger *zerolog.Logger) *TendermintRPC {
+	rpc := &TendermintRPC{
 		NodeConfig:          nodeConfig,
+		ConsensusNodePrefix: consensusNodePrefix,
 		BlocksDiffInThePast: 100,
 		Logger:              logger.With().Str("component", "rpc").Logger(),
 	}
+
+	rpc.Nodes = NewNodesPool(
+		nodeConfig.GetTendermintRPCs(),
+		nodeConfig.FailoverPolicy,
+		rpc.CheckNode,
+		logger,
+	)
+
+	return rpc
+}
+
+// CheckNode returns an error if the node is unreachable or is catching up.
+func (rpc *TendermintRPC) CheckNode(address string) error {
+	client, err := tmrpc.New(address, "/websocket")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RPCHealthCheckTimeout)
+	defer cancel()
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if status.SyncInfo.CatchingUp {
+		return fmt.Errorf("node is catching up")
+	}
+
+	return nil
 }
 
-func (rpc *TendermintRPC) GetAvgBlockTime() float64 {
-	latestBlock := rpc.GetBlock(nil)
-	latestHeight := latestBlock.Height
-	beforeLatestBlockHeight := latestBlock.Height - rpc.BlocksDiffInThePast
-	beforeLatestBlock := rpc.GetBlock(&beforeLatestBlockHeight)
+// Query runs the query against the nodes in the order given by the nodes pool,
+// failing over to the next node if the node could not be reached or didn't answer
+// within Timeout. Errors returned by the node itself, like the requested block
+// not existing, are returned as is.
+func (rpc *TendermintRPC) Query(query func(ctx context.Context, client *tmrpc.HTTP) error) error {
+	var err error
 
-	heightDiff := float64(latestHeight - beforeLatestBlockHeight)
-	timeDiff := latestBlock.Time.Sub(beforeLatestBlock.Time).Seconds()
+	timeout := time.Duration(rpc.NodeConfig.Timeout) * time.Second
+
+	for _, address := range rpc.Nodes.GetNodes() {
+		var client *tmrpc.HTTP
+		if client, err = tmrpc.NewWithTimeout(address, "/websocket", uint(rpc.NodeConfig.Timeout)); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			err = query(ctx, client)
+			cancel()
+		}
+
+		var rpcError *rpctypes.RPCError
+		if err == nil || errors.As(err, &rpcError) {
+			return err
+		}
+
+		rpc.Nodes.SetHealthy(address, err)
+	}
 
-	return timeDiff / heightDiff
+	return err
 }
 
-func (rpc *TendermintRPC) GetBlock(height *int64) *ctypes.Block {
-	client, err := tmrpc.New(rpc.NodeConfig.TendermintRPC, "/websocket")
+func (rpc *TendermintRPC) GetBlock(height *int64) (*ctypes.Block, error) {
+	var block *ctypes.Block
+
+	err := rpc.Query(func(ctx context.Context, client *tmrpc.HTTP) error {
+		result, err := client.Block(ctx, height)
+		if err != nil {
+			return err
+		}
+
+		block = result.Block
+		return nil
+	})
 	if err != nil {
-		rpc.Logger.Fatal().Err(err).Msg("Could not create Tendermint client")
+		rpc.Logger.Error().Err(err).Msg("Could not query for block")
+		return nil, err
 	}
 
-	block, err := client.Block(context.Background(), height)
+	return block, nil
+}
+
+func (rpc *TendermintRPC) GetStatus() (*coretypes.ResultStatus, error) {
+	var status *coretypes.ResultStatus
+
+	err := rpc.Query(func(ctx context.Context, client *tmrpc.HTTP) error {
+		var err error
+		status, err = client.Status(ctx)
+		return err
+	})
+
+	return status, err
+}
+
+func (rpc *TendermintRPC) GetLatestHeight() (int64, error) {
+	status, err := rpc.GetStatus()
 	if err != nil {
-		rpc.Logger.Fatal().Err(err).Msg("Could not query Tendermint status")
+		return 0, err
+	}
+
+	return status.SyncInfo.LatestBlockHeight, nil
+}
+
+// GetBlocksSignatures returns the signatures for each block in [from; to] range.
+// If some block could not be fetched, it returns the blocks fetched before it along with the error.
+func (rpc *TendermintRPC) GetBlocksSignatures(from, to int64) ([]BlockSignatures, error) {
+	blocks := make([]BlockSignatures, 0, to-from+1)
+
+	for height := from; height <= to; height++ {
+		queryHeight := height
+
+		var commit *ctypes.SignedHeader
+
+		err := rpc.Query(func(ctx context.Context, client *tmrpc.HTTP) error {
+			result, err := client.Commit(ctx, &queryHeight)
+			if err != nil {
+				return err
+			}
+
+			commit = &result.SignedHeader
+			return nil
+		})
+		if err != nil {
+			return blocks, err
+		}
+
+		block, err := NewBlockSignatures(*commit, rpc.ConsensusNodePrefix)
+		if err != nil {
+			return blocks, err
+		}
+
+		blocks = append(blocks, block)
 	}
 
-	return block.Block
+	return blocks, nil
 }
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"fmt"
+	"html"
+	"strings"
 	"time"
 
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	evidencetypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
 	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 type Direction int
@@ -15,20 +21,110 @@ const (
 	JAILED
 	UNJAILED
 	TOMBSTONED
+	ACTIVATED
+	DEACTIVATED
+	CREATED
+	REMOVED
+	OFFLINE
+	ONLINE
+	JAIL_WARNING
+	UNJAIL_READY
 )
 
+var directionNames = map[Direction]string{
+	INCREASING:   "increasing",
+	DECREASING:   "decreasing",
+	JAILED:       "jailed",
+	UNJAILED:     "unjailed",
+	TOMBSTONED:   "tombstoned",
+	ACTIVATED:    "activated",
+	DEACTIVATED:  "deactivated",
+	CREATED:      "created",
+	REMOVED:      "removed",
+	OFFLINE:      "offline",
+	ONLINE:       "online",
+	JAIL_WARNING: "jail_warning",
+	UNJAIL_READY: "unjail_ready",
+}
+
+func (d Direction) String() string {
+	if name, ok := directionNames[d]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown(%d)", int(d))
+}
+
+// ParseDirection returns the direction by its name, as returned by Direction.String.
+func ParseDirection(name string) (Direction, bool) {
+	for direction, directionName := range directionNames {
+		if directionName == name {
+			return direction, true
+		}
+	}
+
+	return 0, false
+}
+
+// MarshalText makes directions readable in JSON.
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
 const (
-	TombstonedEmoji = "💀"
-	JailedEmoju     = "❌"
-	UnjailedEmoji   = "👌"
+	TombstonedEmoji  = "💀"
+	JailedEmoju      = "❌"
+	UnjailedEmoji    = "👌"
+	ActivatedEmoji   = "✅"
+	DeactivatedEmoji = "🔻"
+	CreatedEmoji     = "🆕"
+	RemovedEmoji     = "🗑️"
+	OfflineEmoji     = "🚨"
+	OnlineEmoji      = "🆗"
+	JailWarningEmoji = "⏰"
+	UnjailReadyEmoji = "🔓"
 )
 
 const (
-	TombstonedDesc = "was tombstoned"
-	JailedDesc     = "was jailed"
-	UnjailedDesc   = "was unjailed"
+	TombstonedDesc  = "was tombstoned"
+	UnjailedDesc    = "was unjailed"
+	ActivatedDesc   = "has entered the active set"
+	DeactivatedDesc = "has dropped out of the active set"
+	CreatedDesc     = "was created"
+	RemovedDesc     = "was removed from the validators list"
+	OfflineDesc     = "has missed %d blocks in a row"
+	OnlineDesc      = "is signing again after %d-block outage"
+	JailWarningDesc = "is estimated to be jailed in less than %s"
+
+	JailedForDowntimeDesc   = "was jailed for downtime, can be unjailed after %s (in %s)"
+	JailedForDoubleSignDesc = "was jailed for double signing"
+	UnjailReadyDesc         = "can now be unjailed"
+	UnjailReminderDesc      = "is still jailed, while it can be unjailed since %s"
 )
 
+const UnjailTimeFormat = "2006-01-02 15:04 MST"
+
+const WhileOfflineDesc = "while checker was offline"
+
+const (
+	MonitoringDegradedEmoji  = "📵"
+	MonitoringRecoveredEmoji = "📶"
+	MonitoringDegradedDesc   = "Monitoring degraded: cannot reach node, %d polls failed in a row (%s)"
+	MonitoringRecoveredDesc  = "Monitoring recovered: node is reachable again after %d failed polls"
+)
+
+const ParamChangedEmoji = "⚙️"
+
+const (
+	FailedValidatorsEmoji    = "⚠️"
+	FailedValidatorsDesc     = "Could not fetch the state of the following validators"
+	RecoveredValidatorsEmoji = "✔️"
+	RecoveredValidatorsDesc  = "The state of the following validators can be fetched again"
+)
+
+// How many ranges of missed heights to display in a report entry.
+const MaxMissedHeightsRanges = 10
+
 type ValidatorState struct {
 	Address          string
 	Moniker          string
@@ -37,6 +133,40 @@ type ValidatorState struct {
 	Jailed           bool
 	Active           bool
 	Tombstoned       bool
+	JailedUntil      time.Time
+	StartHeight      int64
+	IndexOffset      int64
+}
+
+// JailedForDoubleSign checks whether the validator was jailed for double signing,
+// in which case it's jailed forever, and not for downtime.
+func (s ValidatorState) JailedForDoubleSign() bool {
+	return s.Tombstoned || !s.JailedUntil.Before(evidencetypes.DoubleSignJailEndTime)
+}
+
+// GetJailedDesc returns the jail reason and, if jailed for downtime, when the validator can unjail,
+// estimating it from the downtime jail duration if not known.
+func (s ValidatorState) GetJailedDesc(downtimeJailDuration time.Duration) string {
+	if s.JailedForDoubleSign() {
+		return JailedForDoubleSignDesc
+	}
+
+	// Should not happen, but if the node did not return it, estimating it from the jail duration.
+	unjailTime := s.JailedUntil
+	if unjailTime.IsZero() {
+		unjailTime = time.Now().Add(downtimeJailDuration)
+	}
+
+	timeToUnjail := time.Until(unjailTime).Round(time.Minute)
+	if timeToUnjail < 0 {
+		timeToUnjail = 0
+	}
+
+	return fmt.Sprintf(
+		JailedForDowntimeDesc,
+		unjailTime.UTC().Format(UnjailTimeFormat),
+		FormatDuration(timeToUnjail),
+	)
 }
 
 func NewValidatorState(
@@ -51,11 +181,72 @@ func NewValidatorState(
 		Jailed:           validator.Jailed,
 		Active:           validator.Status == 3, // BOND_STATUS_BONDED
 		Tombstoned:       info.Tombstoned,
+		JailedUntil:      info.JailedUntil,
+		StartHeight:      info.StartHeight,
+		IndexOffset:      info.IndexOffset,
 	}
 }
 
 type ValidatorsState map[string]ValidatorState
 
+// FailedValidator is a validator which state could not be fetched during a poll.
+type FailedValidator struct {
+	Address          string
+	Moniker          string
+	ConsensusAddress string
+	Error            string
+}
+
+type BlockSignatures struct {
+	Height int64
+	Time   time.Time
+	// Consensus addresses of validators who signed the block.
+	// Absent signatures in a commit do not have an address, so a validator
+	// from the active set not being here means it missed the block.
+	Signers map[string]bool
+}
+
+func NewBlockSignatures(header tmtypes.SignedHeader, consensusNodePrefix string) (BlockSignatures, error) {
+	signers := make(map[string]bool, len(header.Commit.Signatures))
+
+	for _, signature := range header.Commit.Signatures {
+		if signature.Absent() {
+			continue
+		}
+
+		address, err := bech32.ConvertAndEncode(consensusNodePrefix, signature.ValidatorAddress)
+		if err != nil {
+			return BlockSignatures{}, err
+		}
+
+		signers[address] = true
+	}
+
+	return BlockSignatures{
+		Height:  header.Height,
+		Time:    header.Time,
+		Signers: signers,
+	}, nil
+}
+
+// GetMissedHeights returns the heights each of the active validators from state
+// missed, keyed by consensus address.
+func GetMissedHeights(blocks []BlockSignatures, state ValidatorsState) map[string][]int64 {
+	missedHeights := make(map[string][]int64)
+
+	for _, block := range blocks {
+		for address, validator := range state {
+			if !validator.Active || block.Signers[address] {
+				continue
+			}
+
+			missedHeights[address] = append(missedHeights[address], block.Height)
+		}
+	}
+
+	return missedHeights
+}
+
 type ReportEntry struct {
 	ValidatorAddress string
 	ValidatorMoniker string
@@ -63,8 +254,17 @@ type ReportEntry struct {
 	Description      string
 	MissingBlocks    int64
 	Direction        Direction
+	WhileOffline     bool
+	MissedHeights    []int64
+	// Index of the missed blocks group the validator has moved to,
+	// only set for INCREASING and DECREASING entries.
+	Group int
+	// Share of blocks the validator is missing at the moment, from 0 to 1.
+	MissRate      float64
+	MissRateKnown bool
 }
 
+// GetTimeToJail returns the time till jail if the validator misses all the remaining blocks.
 func (r ReportEntry) GetTimeToJail(params *Params) time.Duration {
 	blocksLeftToJail := params.MissedBlocksToJail - r.MissingBlocks
 	secondsLeftToJail := params.AvgBlockTime * float64(blocksLeftToJail)
@@ -72,8 +272,183 @@ func (r ReportEntry) GetTimeToJail(params *Params) time.Duration {
 	return time.Duration(secondsLeftToJail) * time.Second
 }
 
+// GetTimeToJailAtMissRate returns the time till jail if the validator keeps missing
+// blocks at its current miss rate, and false if it's not going to be jailed at this rate.
+func (r ReportEntry) GetTimeToJailAtMissRate(params *Params) (time.Duration, bool) {
+	if !r.MissRateKnown || r.MissRate <= 0 {
+		return 0, false
+	}
+
+	blocksLeftToJail := float64(params.MissedBlocksToJail-r.MissingBlocks) / r.MissRate
+	secondsLeftToJail := params.AvgBlockTime * blocksLeftToJail
+
+	return time.Duration(secondsLeftToJail) * time.Second, true
+}
+
+// GetTimeToJailDesc returns the time till jail at the current miss rate along with
+// the worst case one, or only the latter if the validator is fully offline
+// or its miss rate is not known.
+func (r ReportEntry) GetTimeToJailDesc(params *Params) string {
+	timeToJail := r.GetTimeToJail(params)
+
+	if r.MissRateKnown && r.MissRate < 1 {
+		timeToJailAtMissRate, ok := r.GetTimeToJailAtMissRate(params)
+		if !ok {
+			return fmt.Sprintf("not missing blocks now, ~%s till jail if fully offline", FormatEstimate(timeToJail))
+		}
+
+		return fmt.Sprintf(
+			"~%s till jail at current rate, ~%s if fully offline",
+			FormatEstimate(timeToJailAtMissRate),
+			FormatEstimate(timeToJail),
+		)
+	}
+
+	return fmt.Sprintf("~%s till jail", FormatEstimate(timeToJail))
+}
+
+// MonitoringAlert is about the checker itself and not about any validator,
+// like the checker not being able to reach the node.
+type MonitoringAlert struct {
+	Emoji       string
+	Description string
+}
+
+// ParamChange is a change of a chain param affecting the alerts, like the signed blocks window.
+type ParamChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
 type Report struct {
-	Entries []ReportEntry
+	// Empty if monitoring a single chain without a name set.
+	ChainName       string
+	Time            time.Time
+	ChainInfoConfig ChainInfoConfig
+	Params          Params
+	// Number of missed blocks groups, to tell how far the entries groups are from the first one.
+	GroupsCount int
+
+	MonitoringAlerts []MonitoringAlert
+	ParamsChanges    []ParamChange
+	Entries          []ReportEntry
+	// Validators which state could not be fetched since this poll, so they might have
+	// changes not reported until they can be fetched again. Not repeated while they keep failing.
+	FailedValidators []FailedValidator
+	// Validators which state could be fetched again after failing.
+	RecoveredValidators []FailedValidator
+}
+
+// SerializeHTML renders the report as HTML lines, with links to validators pages.
+// If entrySuffix is set, its result is appended to each entry line.
+func (r *Report) SerializeHTML(entrySuffix func(entry ReportEntry) string) string {
+	var sb strings.Builder
+
+	if r.ChainName != "" {
+		sb.WriteString(fmt.Sprintf("<strong>%s</strong>\n", html.EscapeString(r.ChainName)))
+	}
+
+	for _, alert := range r.MonitoringAlerts {
+		sb.WriteString(fmt.Sprintf("%s <strong>%s</strong>\n", alert.Emoji, html.EscapeString(alert.Description)))
+	}
+
+	for _, change := range r.ParamsChanges {
+		sb.WriteString(fmt.Sprintf(
+			"%s <strong>%s changed: %s → %s</strong>\n",
+			ParamChangedEmoji,
+			change.Name,
+			change.Old,
+			change.New,
+		))
+	}
+
+	for _, entry := range r.Entries {
+		var (
+			timeToJail    = ""
+			whileOffline  = ""
+			missedHeights = ""
+			suffix        = ""
+		)
+
+		if (entry.Direction == INCREASING || entry.Direction == JAIL_WARNING) && r.Params.AvgBlockTimeKnown() {
+			timeToJail = fmt.Sprintf(" (%s)", entry.GetTimeToJailDesc(&r.Params))
+		}
+
+		if entry.WhileOffline {
+			whileOffline = fmt.Sprintf(" (%s)", WhileOfflineDesc)
+		}
+
+		if len(entry.MissedHeights) > 0 {
+			missedHeights = fmt.Sprintf(
+				" (missed blocks: %s)",
+				FormatHeights(entry.MissedHeights, MaxMissedHeightsRanges),
+			)
+		}
+
+		if entrySuffix != nil {
+			suffix = entrySuffix(entry)
+		}
+
+		sb.WriteString(fmt.Sprintf(
+			"%s <strong>%s %s</strong>%s%s%s%s\n",
+			entry.Emoji,
+			r.ChainInfoConfig.GetValidatorPage(entry.ValidatorAddress, entry.ValidatorMoniker),
+			html.EscapeString(entry.Description),
+			timeToJail,
+			missedHeights,
+			whileOffline,
+			suffix,
+		))
+	}
+
+	if len(r.FailedValidators) > 0 {
+		sb.WriteString(fmt.Sprintf(
+			"%s <i>%s: %s</i>\n",
+			FailedValidatorsEmoji,
+			FailedValidatorsDesc,
+			r.GetValidatorsPages(r.FailedValidators),
+		))
+	}
+
+	if len(r.RecoveredValidators) > 0 {
+		sb.WriteString(fmt.Sprintf(
+			"%s <i>%s: %s</i>\n",
+			RecoveredValidatorsEmoji,
+			RecoveredValidatorsDesc,
+			r.GetValidatorsPages(r.RecoveredValidators),
+		))
+	}
+
+	return sb.String()
+}
+
+// GetValidatorsPages returns the comma-separated links to the validators pages.
+func (r *Report) GetValidatorsPages(validators []FailedValidator) string {
+	links := make([]string, len(validators))
+	for index, validator := range validators {
+		links[index] = r.ChainInfoConfig.GetValidatorPage(validator.Address, validator.Moniker)
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// GetChainName returns the chain name, or its Mintscan prefix if it's not set.
+func (r *Report) GetChainName() string {
+	if r.ChainName != "" {
+		return r.ChainName
+	}
+
+	return r.ChainInfoConfig.MintscanPrefix
+}
+
+// Empty returns true if there's nothing worth sending in the report.
+func (r *Report) Empty() bool {
+	return len(r.Entries) == 0 &&
+		len(r.MonitoringAlerts) == 0 &&
+		len(r.ParamsChanges) == 0 &&
+		len(r.FailedValidators) == 0 &&
+		len(r.RecoveredValidators) == 0
 }
 
 type Reporter interface {
@@ -1,5 +1,29 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	htmlLinkRegexp = regexp.MustCompile(`<a href="([^"]*)">([^<]*)</a>`)
+	htmlTagRegexp  = regexp.MustCompile(`<[^>]+>`)
+)
+
+const (
+	RetryInitialDelay = time.Second
+	RetryMaxDelay     = time.Minute
+)
+
 func stringInSlice(first string, list []string) bool {
 	for _, second := range list {
 		if first == second {
@@ -39,3 +63,157 @@ func MapToSlice[T any](source map[string]T) []T {
 
 	return n
 }
+
+// FormatHeights collapses the sorted heights into ranges, like "100-105, 110",
+// listing no more than maxRanges of them.
+func FormatHeights(heights []int64, maxRanges int) string {
+	ranges := []string{}
+
+	for i := 0; i < len(heights); {
+		j := i
+		for j+1 < len(heights) && heights[j+1] == heights[j]+1 {
+			j++
+		}
+
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("%d", heights[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", heights[i], heights[j]))
+		}
+
+		i = j + 1
+	}
+
+	if len(ranges) > maxRanges {
+		return fmt.Sprintf("%s and %d more", strings.Join(ranges[:maxRanges], ", "), len(ranges)-maxRanges)
+	}
+
+	return strings.Join(ranges, ", ")
+}
+
+// RetryWithBackoff calls f until it succeeds, doubling the delay between attempts
+// up to RetryMaxDelay. The delay is randomized, so monitors of multiple chains
+// talking to the same node do not retry all at once.
+func RetryWithBackoff(logger *zerolog.Logger, action string, f func() error) {
+	_ = RetryWithBackoffAttempts(logger, action, 0, f)
+}
+
+// PermanentError is an error retrying won't help with, like a request being rejected.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// RetryWithBackoffAttempts is RetryWithBackoff giving up after the given amount
+// of attempts, 0 meaning never, or on a PermanentError, and returning the last error.
+func RetryWithBackoffAttempts(logger *zerolog.Logger, action string, attempts int, f func() error) error {
+	delay := RetryInitialDelay
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		var permanentErr *PermanentError
+		if errors.As(err, &permanentErr) {
+			logger.Warn().Err(err).Int("attempt", attempt).Msg("Could not " + action + ", not retrying")
+			return err
+		}
+
+		if attempts > 0 && attempt >= attempts {
+			logger.Warn().Err(err).Int("attempt", attempt).Msg("Could not " + action + ", giving up")
+			return err
+		}
+
+		jitteredDelay := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		logger.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("retryIn", jitteredDelay).
+			Msg("Could not " + action + ", retrying")
+		time.Sleep(jitteredDelay)
+
+		delay *= 2
+		if delay > RetryMaxDelay {
+			delay = RetryMaxDelay
+		}
+	}
+}
+
+// FormatDuration formats the duration without the zero minutes and seconds,
+// like 2h instead of 2h0m0s.
+func FormatDuration(duration time.Duration) string {
+	formatted := duration.String()
+
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = formatted[:len(formatted)-2]
+	}
+
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = formatted[:len(formatted)-2]
+	}
+
+	return formatted
+}
+
+// FormatEstimate rounds the estimated duration to a precision making sense for its size
+// and formats it, like 3h instead of 2h59m59.5s.
+func FormatEstimate(duration time.Duration) string {
+	switch {
+	case duration >= 24*time.Hour:
+		duration = duration.Round(time.Hour)
+	case duration >= time.Hour:
+		duration = duration.Round(10 * time.Minute)
+	case duration >= time.Minute:
+		duration = duration.Round(time.Minute)
+	default:
+		duration = duration.Round(time.Second)
+	}
+
+	return FormatDuration(duration)
+}
+
+// HTMLToPlainText converts the HTML used in reports to plain text,
+// keeping links URLs after their text.
+func HTMLToPlainText(text string) string {
+	text = htmlLinkRegexp.ReplaceAllString(text, "$2 ($1)")
+	return html.UnescapeString(htmlTagRegexp.ReplaceAllString(text, ""))
+}
+
+// TruncateString cuts the string to the given amount of characters, ending it
+// with an ellipsis if it was cut, never splitting a multi-byte character.
+func TruncateString(text string, length int) string {
+	if utf8.RuneCountInString(text) <= length {
+		return text
+	}
+
+	runes := []rune(text)
+	return string(runes[:length-3]) + "..."
+}
+
+// NewHTTPStatusError returns the error for a non-successful response, which is permanent
+// for client errors except for being rate limited.
+func NewHTTPStatusError(statusCode int, body []byte) error {
+	err := fmt.Errorf("got status %d: %s", statusCode, body)
+	if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+
+	return err
+}
+
+func BoolToFloat64(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
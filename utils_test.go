@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatHeights(t *testing.T) {
+	testCases := []struct {
+		name      string
+		heights   []int64
+		maxRanges int
+		expected  string
+	}{
+		{"no heights", []int64{}, 5, ""},
+		{"single height", []int64{100}, 5, "100"},
+		{"separate heights", []int64{100, 102, 104}, 5, "100, 102, 104"},
+		{"consecutive heights", []int64{100, 101, 102}, 5, "100-102"},
+		{"mixed heights", []int64{100, 101, 103, 105, 106, 107}, 5, "100-101, 103, 105-107"},
+		{"too many ranges", []int64{100, 102, 103, 105, 107, 109}, 2, "100, 102-103 and 3 more"},
+		{"exactly max ranges", []int64{100, 102}, 2, "100, 102"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if formatted := FormatHeights(testCase.heights, testCase.maxRanges); formatted != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, formatted)
+			}
+		})
+	}
+}
+
+func TestFormatEstimate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		duration time.Duration
+		expected string
+	}{
+		{"seconds", 1500 * time.Millisecond, "2s"},
+		{"minutes", 5*time.Minute + 29*time.Second, "5m"},
+		{"exact minutes", 30 * time.Minute, "30m"},
+		{"hours", 2*time.Hour + 59*time.Minute + 59*time.Second, "3h"},
+		{"hours and minutes", time.Hour + 34*time.Minute, "1h30m"},
+		{"days", 49*time.Hour + 40*time.Minute, "50h"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if formatted := FormatEstimate(testCase.duration); formatted != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, formatted)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const WebhookSignatureHeader = "X-Signature-256"
+
+// WebhookReporter POSTs each report as JSON to the configured URLs. If a secret is set,
+// requests are signed with HMAC-SHA256 of the body, passed as "sha256=<hex>"
+// in the X-Signature-256 header, so the receiver can verify them.
+type WebhookReporter struct {
+	WebhookConfig WebhookConfig
+	Logger        zerolog.Logger
+
+	Client *http.Client
+}
+
+type WebhookPayload struct {
+	Chain            string                   `json:"chain"`
+	Timestamp        time.Time                `json:"timestamp"`
+	MonitoringAlerts []WebhookMonitoringAlert `json:"monitoring_alerts"`
+	ParamsChanges    []WebhookParamChange     `json:"params_changes"`
+	Entries          []WebhookEntry           `json:"entries"`
+	FailedValidators []WebhookFailedValidator `json:"failed_validators"`
+	// Validators which were failing before and could be fetched this time.
+	RecoveredValidators []WebhookRecoveredValidator `json:"recovered_validators"`
+}
+
+type WebhookMonitoringAlert struct {
+	Emoji       string `json:"emoji"`
+	Description string `json:"description"`
+}
+
+type WebhookParamChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+type WebhookEntry struct {
+	Chain            string    `json:"chain"`
+	ValidatorAddress string    `json:"validator_address"`
+	Moniker          string    `json:"moniker"`
+	Direction        Direction `json:"direction"`
+	MissedBlocks     int64     `json:"missed_blocks"`
+	Window           int64     `json:"window"`
+	// Only set for validators missing blocks and if the block time is known.
+	TimeToJailSeconds           *float64  `json:"time_to_jail_seconds,omitempty"`
+	TimeToJailAtMissRateSeconds *float64  `json:"time_to_jail_at_miss_rate_seconds,omitempty"`
+	MissedHeights               []int64   `json:"missed_heights,omitempty"`
+	WhileOffline                bool      `json:"while_offline"`
+	Emoji                       string    `json:"emoji"`
+	Description                 string    `json:"description"`
+	Timestamp                   time.Time `json:"timestamp"`
+}
+
+type WebhookFailedValidator struct {
+	ValidatorAddress string `json:"validator_address"`
+	Moniker          string `json:"moniker"`
+	Error            string `json:"error"`
+}
+
+type WebhookRecoveredValidator struct {
+	ValidatorAddress string `json:"validator_address"`
+	Moniker          string `json:"moniker"`
+}
+
+func NewWebhookReporter(
+	webhookConfig WebhookConfig,
+	logger *zerolog.Logger,
+) *WebhookReporter {
+	return &WebhookReporter{
+		WebhookConfig: webhookConfig,
+		Logger:        logger.With().Str("component", "webhook_reporter").Logger(),
+	}
+}
+
+// NewWebhookPayload converts the report to the JSON payload, which is also
+// how the reports are returned by the API.
+func NewWebhookPayload(report Report) WebhookPayload {
+	payload := WebhookPayload{
+		Chain:            report.ChainName,
+		Timestamp:        report.Time,
+		MonitoringAlerts: make([]WebhookMonitoringAlert, len(report.MonitoringAlerts)),
+		ParamsChanges:    make([]WebhookParamChange, len(report.ParamsChanges)),
+		Entries:          make([]WebhookEntry, len(report.Entries)),
+		FailedValidators: make([]WebhookFailedValidator, len(report.FailedValidators)),
+
+		RecoveredValidators: make([]WebhookRecoveredValidator, len(report.RecoveredValidators)),
+	}
+
+	for index, alert := range report.MonitoringAlerts {
+		payload.MonitoringAlerts[index] = WebhookMonitoringAlert{
+			Emoji:       alert.Emoji,
+			Description: alert.Description,
+		}
+	}
+
+	for index, change := range report.ParamsChanges {
+		payload.ParamsChanges[index] = WebhookParamChange{
+			Name: change.Name,
+			Old:  change.Old,
+			New:  change.New,
+		}
+	}
+
+	for index, entry := range report.Entries {
+		webhookEntry := WebhookEntry{
+			Chain:            report.ChainName,
+			ValidatorAddress: entry.ValidatorAddress,
+			Moniker:          entry.ValidatorMoniker,
+			Direction:        entry.Direction,
+			MissedBlocks:     entry.MissingBlocks,
+			Window:           report.Params.SignedBlocksWindow,
+			MissedHeights:    entry.MissedHeights,
+			WhileOffline:     entry.WhileOffline,
+			Emoji:            entry.Emoji,
+			Description:      entry.Description,
+			Timestamp:        report.Time,
+		}
+
+		if (entry.Direction == INCREASING || entry.Direction == JAIL_WARNING) && report.Params.AvgBlockTimeKnown() {
+			timeToJail := entry.GetTimeToJail(&report.Params).Seconds()
+			webhookEntry.TimeToJailSeconds = &timeToJail
+
+			if timeToJailAtMissRate, ok := entry.GetTimeToJailAtMissRate(&report.Params); ok {
+				seconds := timeToJailAtMissRate.Seconds()
+				webhookEntry.TimeToJailAtMissRateSeconds = &seconds
+			}
+		}
+
+		payload.Entries[index] = webhookEntry
+	}
+
+	for index, validator := range report.FailedValidators {
+		payload.FailedValidators[index] = WebhookFailedValidator{
+			ValidatorAddress: validator.Address,
+			Moniker:          validator.Moniker,
+			Error:            validator.Error,
+		}
+	}
+
+	for index, validator := range report.RecoveredValidators {
+		payload.RecoveredValidators[index] = WebhookRecoveredValidator{
+			ValidatorAddress: validator.Address,
+			Moniker:          validator.Moniker,
+		}
+	}
+
+	return payload
+}
+
+func (r WebhookReporter) Serialize(report Report) string {
+	payload, err := json.Marshal(NewWebhookPayload(report))
+	if err != nil {
+		r.Logger.Error().Err(err).Msg("Could not serialize report")
+		return ""
+	}
+
+	return string(payload)
+}
+
+func (r *WebhookReporter) Init() {
+	if len(r.WebhookConfig.URLs) == 0 {
+		r.Logger.Debug().Msg("Webhook URLs not set, not creating webhook reporter.")
+		return
+	}
+
+	r.Client = &http.Client{
+		Timeout: time.Duration(r.WebhookConfig.Timeout) * time.Second,
+	}
+}
+
+func (r WebhookReporter) Enabled() bool {
+	return len(r.WebhookConfig.URLs) > 0
+}
+
+// SendReport sends the report to all the URLs, retrying each one separately.
+func (r WebhookReporter) SendReport(report Report) error {
+	body := []byte(r.Serialize(report))
+	failedURLs := []string{}
+
+	for _, url := range r.WebhookConfig.URLs {
+		err := RetryWithBackoffAttempts(&r.Logger, "send webhook", r.WebhookConfig.Attempts, func() error {
+			return r.send(url, body)
+		})
+		if err != nil {
+			failedURLs = append(failedURLs, url)
+		}
+	}
+
+	if len(failedURLs) > 0 {
+		return fmt.Errorf("could not send webhook to %s", strings.Join(failedURLs, ", "))
+	}
+
+	return nil
+}
+
+func (r WebhookReporter) send(url string, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for header, value := range r.WebhookConfig.Headers {
+		request.Header.Set(header, value)
+	}
+
+	if r.WebhookConfig.Secret != "" {
+		request.Header.Set(WebhookSignatureHeader, "sha256="+SignHMACSHA256(r.WebhookConfig.Secret, body))
+	}
+
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return NewHTTPStatusError(response.StatusCode, responseBody)
+	}
+
+	return nil
+}
+
+func (r WebhookReporter) Name() string {
+	return "WebhookReporter"
+}
+
+// SignHMACSHA256 returns the hex-encoded HMAC-SHA256 of the body.
+func SignHMACSHA256(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}